@@ -0,0 +1,257 @@
+package di
+
+import (
+	"encoding"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ConfigOption configures the sources of ProvideConfig.
+type ConfigOption func(c *configLoader)
+
+type configLoader struct {
+	file     string
+	decoders map[string]func(data []byte, v interface{}) error
+	prefix   string
+	flags    *flag.FlagSet
+	args     []string
+}
+
+// ConfigFile load the configuration from the file, the format is decided by the file extension, only JSON is
+// supported by default, others could be added by ConfigDecoder.
+func ConfigFile(path string) ConfigOption {
+	return func(c *configLoader) {
+		c.file = path
+	}
+}
+
+// ConfigDecoder add the decoder for files of the extension such as ".yaml", it's compatible with
+// json.Unmarshal, yaml.Unmarshal and toml.Unmarshal of common libraries.
+func ConfigDecoder(ext string, decode func(data []byte, v interface{}) error) ConfigOption {
+	return func(c *configLoader) {
+		c.decoders[strings.ToLower(ext)] = decode
+	}
+}
+
+// ConfigEnvPrefix set the prefix of environment variables, such as "APP_".
+func ConfigEnvPrefix(prefix string) ConfigOption {
+	return func(c *configLoader) {
+		c.prefix = prefix
+	}
+}
+
+// ConfigFlags define flags for fields tagged with `flag:"name"` on the flag set and parse the arguments, a
+// new flag set is created if it's nil. Fields tagged with `usage:"..."` use it as the usage of the flag.
+func ConfigFlags(fs *flag.FlagSet, args []string) ConfigOption {
+	return func(c *configLoader) {
+		if fs == nil {
+			fs = flag.NewFlagSet(os.Args[0], flag.ContinueOnError)
+		}
+		c.flags = fs
+		c.args = args
+	}
+}
+
+// ProvideConfig fill the structure pointed by cfg and provide it as a static value, initial values of the
+// structure are defaults, then they are overridden by the file, environment variables of fields tagged with
+// `env:"NAME"` and command-line flags of fields tagged with `flag:"name"` in order. Fields of nested
+// structures are filled too.
+// The pointer could be wrapped by OptNamed to name the structure, OptDecompose to provide fields as
+// dependencies too, and other options such as OptProfile like Provide, the config is not loaded if it's
+// disabled.
+func (j *Injector) ProvideConfig(cfg interface{}, opts ...ConfigOption) error {
+	o := parseOptionValue(cfg)
+	if o.Value.Kind() != reflect.Ptr || o.Value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config must be pointer of structure: %s", o.Value.Type())
+	}
+	if !j.enabled(o) {
+		return nil
+	}
+	c := configLoader{
+		decoders: map[string]func(data []byte, v interface{}) error{
+			".json": json.Unmarshal,
+		},
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	err := c.load(o.Value)
+	if err != nil {
+		return err
+	}
+
+	o.Value = o.Value.Elem()
+	providers := []interface{}{o}
+	if o.Decomposable {
+		// the structure itself is provided too.
+		o.Decomposable = false
+		providers = append(providers, o)
+	}
+	return j.Provide(providers...)
+}
+
+func (c *configLoader) load(ptr reflect.Value) error {
+	if c.file != "" {
+		ext := strings.ToLower(filepath.Ext(c.file))
+		decode, has := c.decoders[ext]
+		if !has {
+			return fmt.Errorf("config decoder not found for file: %s", c.file)
+		}
+		data, err := os.ReadFile(c.file)
+		if err != nil {
+			return err
+		}
+		err = decode(data, ptr.Interface())
+		if err != nil {
+			return fmt.Errorf("decode config file %s failed: %w", c.file, err)
+		}
+	}
+	err := c.walk(ptr.Elem(), func(v reflect.Value, f reflect.StructField) error {
+		name := f.Tag.Get("env")
+		if name == "" {
+			return nil
+		}
+		s, has := os.LookupEnv(c.prefix + name)
+		if !has {
+			return nil
+		}
+		err := setConfigValue(v, s)
+		if err != nil {
+			return fmt.Errorf("invalid env %s: %w", c.prefix+name, err)
+		}
+		return nil
+	})
+	if err != nil || c.flags == nil {
+		return err
+	}
+	err = c.walk(ptr.Elem(), func(v reflect.Value, f reflect.StructField) error {
+		if name := f.Tag.Get("flag"); name != "" {
+			c.flags.Var(configFlag{v}, name, f.Tag.Get("usage"))
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	return c.flags.Parse(c.args)
+}
+
+// walk visit exported fields of the structure, fields of nested structures are visited recursively unless
+// they are tagged or could be parsed from string.
+func (c *configLoader) walk(v reflect.Value, fn func(v reflect.Value, f reflect.StructField) error) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		fv := v.Field(i)
+		tagged := f.Tag.Get("env") != "" || f.Tag.Get("flag") != ""
+		if !tagged && fv.Kind() == reflect.Struct && !isTextValue(fv) {
+			err := c.walk(fv, fn)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		err := fn(fv, f)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var (
+	durationReftype        = reflect.TypeOf(time.Duration(0))
+	textUnmarshalerReftype = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+)
+
+func isTextValue(v reflect.Value) bool {
+	return v.CanAddr() && v.Addr().Type().Implements(textUnmarshalerReftype)
+}
+
+// setConfigValue parse the string to the value, elements of slice are separated by comma.
+func setConfigValue(v reflect.Value, s string) error {
+	if isTextValue(v) {
+		return v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(s))
+	}
+	if v.Type() == durationReftype {
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 0, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		var items []string
+		if s != "" {
+			items = strings.Split(s, ",")
+		}
+		sv := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			err := setConfigValue(sv.Index(i), strings.TrimSpace(item))
+			if err != nil {
+				return err
+			}
+		}
+		v.Set(sv)
+	default:
+		return fmt.Errorf("unsupported config type: %s", v.Type())
+	}
+	return nil
+}
+
+type configFlag struct {
+	v reflect.Value
+}
+
+func (f configFlag) String() string {
+	if !f.v.IsValid() {
+		return ""
+	}
+	return fmt.Sprint(f.v.Interface())
+}
+
+func (f configFlag) Set(s string) error {
+	return setConfigValue(f.v, s)
+}
+
+func (f configFlag) IsBoolFlag() bool {
+	return f.v.IsValid() && f.v.Kind() == reflect.Bool
+}
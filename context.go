@@ -0,0 +1,90 @@
+package di
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+var contextReftype = reflect.TypeOf((*context.Context)(nil)).Elem()
+
+// OptTimeout limit the running time of the function provider, the context.Context parameter of the
+// provider will be canceled after the timeout, and the provider fails with TimeoutError if it's not
+// finished. The provider should respect the context, otherwise it keeps running in background.
+func OptTimeout(timeout time.Duration, v interface{}) interface{} {
+	o := parseOptionValue(v)
+	o.Timeout = timeout
+	return o
+}
+
+// TimeoutError indicates that the provider is not finished in the timeout specified by OptTimeout,
+// it matches context.DeadlineExceeded by errors.Is.
+type TimeoutError struct {
+	Provider string
+	Timeout  time.Duration
+}
+
+func (e *TimeoutError) Error() string {
+	return fmt.Sprintf("provider %s timeout after %s", e.Provider, e.Timeout)
+}
+
+func (e *TimeoutError) Unwrap() error {
+	return context.DeadlineExceeded
+}
+
+type contextParser struct{}
+
+func (contextParser) Parse(r *resolution) (reflect.Value, error) {
+	ctx := r.context()
+	return reflect.ValueOf(&ctx).Elem(), nil
+}
+
+func (contextParser) Resolve(r *resolution, v reflect.Value) error {
+	return nil
+}
+
+func (c contextParser) Inject(v reflect.Value, r *resolution) error {
+	ctx, err := c.Parse(r)
+	if err != nil {
+		return err
+	}
+	v.Set(ctx)
+	return nil
+}
+
+type providerResult struct {
+	out []reflect.Value
+	err error
+}
+
+// runProviderTimeout run the provider in a new goroutine with a private resolution and wait for it at
+// most the timeout, outputs are resolved and hooks are registered only if it's finished in time, late
+// results are dropped.
+func (j *Injector) runProviderTimeout(p *provider, r *resolution) error {
+	parent := r.context()
+	ctx, cancel := context.WithTimeout(parent, p.timeout)
+	defer cancel()
+
+	tr := r.private()
+	tr.ctx = ctx
+	tr.timed = &timedHooks{provider: p}
+	done := make(chan providerResult, 1)
+	go func() {
+		out, err := j.callFunc(p, tr)
+		done <- providerResult{out: out, err: err}
+	}()
+	select {
+	case res := <-done:
+		if res.err != nil {
+			return res.err
+		}
+		p.hooks = append(p.hooks, tr.timed.hooks...)
+		return j.resolveOutputs(p, r, res.out)
+	case <-ctx.Done():
+		if parent.Err() != nil {
+			return parent.Err()
+		}
+		return &TimeoutError{Provider: p.name, Timeout: p.timeout}
+	}
+}
@@ -0,0 +1,83 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Decorate register decorators of provided dependencies, a decorator is a function returns replacements
+// of dependencies, and a parameter of the same type receives the value before decorating, other
+// parameters are normal dependencies. Decorated dependencies are matched by type, a structure result
+// with field tags is used to decorate named dependencies.
+// Decorators of a dependency are chained in registration order, all of them are run after the original
+// provider and before any consumers of the dependency, even if they don't receive the value before decorating. Only singleton dependencies provided by the
+// injector itself could be decorated, so decorators should be registered after the providers.
+// The original value is still stopped by it's provider in Shutdown, decorators should stop their results
+// by Lifecycle if necessary.
+func (j *Injector) Decorate(v ...interface{}) error {
+	decorators := make([]interface{}, 0, len(v))
+	for _, arg := range v {
+		o := parseOptionValue(arg)
+		o.Decorator = true
+		decorators = append(decorators, o)
+	}
+	return j.Provide(decorators...)
+}
+
+func (j *Injector) decorate(opt optionValue) error {
+	if opt.Value.Kind() != reflect.Func {
+		return fmt.Errorf("decorator must be function: %s", opt.Value.Type())
+	}
+	p, err := j.analyseFunc(opt.Name, opt.Value.Type(), opt.Value)
+	if err != nil {
+		return err
+	}
+	if len(p.provides) == 0 {
+		return fmt.Errorf("decorator decorates nothing: %s", p.name)
+	}
+	mods := make([]*dependency, 0, len(p.provides))
+	for _, d := range p.provides {
+		m := j.deps.match(d)
+		if m == nil {
+			return d.notExistError(p.name)
+		}
+		if m.Provider.lifetime != lifetimeSingleton {
+			return fmt.Errorf("only singleton dependency could be decorated: %s", m.String())
+		}
+		mods = append(mods, m)
+	}
+	p.decorator = true
+	for _, m := range mods {
+		m.decorators = append(m.decorators, p)
+	}
+	j.providers = append(j.providers, p)
+	j.event(Event{Kind: EventProvide, Provider: p.name, Provides: p.provideNames()})
+	return nil
+}
+
+// decorated returns dependencies decorated by the decorator, they may be replaced after decorating.
+func (j *Injector) decorated(p *provider) []*dependency {
+	if !p.decorator {
+		return nil
+	}
+	mods := make([]*dependency, 0, len(p.provides))
+	for _, d := range p.provides {
+		if m := j.matchLocal(d); m != nil {
+			mods = append(mods, m)
+		}
+	}
+	return mods
+}
+
+// providers returns the provider of the dependency and it's decorators should be finished before the
+// consumer, a decorator only waits for decorators registered before it.
+func (d *dependency) providers(consumer *provider) []*provider {
+	providers := []*provider{d.Provider}
+	for _, p := range d.decorators {
+		if p == consumer {
+			break
+		}
+		providers = append(providers, p)
+	}
+	return providers
+}
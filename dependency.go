@@ -1,25 +1,35 @@
 package di
 
 import (
-	"fmt"
 	"reflect"
+	"sort"
+	"sync"
+	"time"
 )
 
 type (
 	dependency struct {
-		Type reflect.Type
-		Var  string
+		Type     reflect.Type
+		Var      string
+		Group    string
+		Map      bool
+		Optional bool
+		Lazy     bool
 
-		Val      reflect.Value
-		Provider *provider
+		Val        reflect.Value
+		Provider   *provider
+		decorators []*provider
+		origin     reflect.Value
+		replaced   bool
+		module     *module
 	}
 
 	dependencies map[reflect.Type][]*dependency
 
 	depTool interface {
-		Parse(src dependencies) (dst reflect.Value, err error)
-		Resolve(dst dependencies, src reflect.Value) error
-		Inject(dst reflect.Value, src dependencies) error
+		Parse(r *resolution) (dst reflect.Value, err error)
+		Resolve(r *resolution, src reflect.Value) error
+		Inject(dst reflect.Value, r *resolution) error
 	}
 
 	provider struct {
@@ -32,11 +42,20 @@ type (
 		provides         []*dependency
 		provideResolvers []depTool
 		errorResolver    errorResolver
+		lifetime         lifetime
+		timeout          time.Duration
+
+		mu        sync.Mutex
+		deferred  bool
+		decorator bool
+		timing    providerTiming
+
+		hooks []Hook
 	}
 )
 
 func (m dependencies) match(d *dependency) *dependency {
-	deps := m[d.Type]
+	deps := closest(m[d.Type], d.module)
 	l := len(deps)
 	if l == 0 {
 		return nil
@@ -53,72 +72,130 @@ func (m dependencies) match(d *dependency) *dependency {
 		if mod.Var == d.Var {
 			return mod
 		}
-		if mod.Var == "" {
+		if mod.Var == "" && def == nil {
 			def = mod
 		}
 	}
 	return def
 }
 
+// implementations find dependencies whose type implements the interface type of d, dependencies
+// have the same name are preferred.
+func (m dependencies) implementations(d *dependency) []*dependency {
+	var all, named []*dependency
+	for t, deps := range m {
+		if t == d.Type || !t.Implements(d.Type) {
+			continue
+		}
+		for _, dep := range deps {
+			if !dep.visibleTo(d.module) {
+				continue
+			}
+			all = append(all, dep)
+			if dep.Var == d.Var {
+				named = append(named, dep)
+			}
+		}
+	}
+	if len(named) > 0 {
+		all = named
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].String() < all[j].String()
+	})
+	return all
+}
+
 func (d *dependency) String() string {
 	n := d.Type.String()
 	if d.Var != "" {
 		n += "#" + d.Var
 	}
+	if d.Group != "" {
+		n += "#group:" + d.Group
+	}
+	if d.Map {
+		n += "#map"
+	}
 	return n
 }
 
 func (d *dependency) notExistError(provider string) error {
-	n := d.String()
-	if provider == "" {
-		return fmt.Errorf("dependency %s not found", n)
+	return &MissingDependencyError{
+		Dependency: d.String(),
+		Provider:   provider,
+	}
+}
+
+func (d *dependency) ambiguousError(provider string, candidates []*dependency) error {
+	names := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		names = append(names, c.String())
+	}
+	return &AmbiguousError{
+		Dependency: d.String(),
+		Provider:   provider,
+		Candidates: names,
 	}
-	return fmt.Errorf("dependency %s not found for provider %s", n, provider)
 }
 
 func (d *dependency) notInitializedError(provider string) error {
-	n := d.String()
-	if provider == "" {
-		return fmt.Errorf("dependency %s not initialized", n)
+	return &UninitializedError{
+		Dependency: d.String(),
+		Provider:   provider,
 	}
-	return fmt.Errorf("dependency %s not initialized for provider %s", n, provider)
 }
 
-func (d *dependency) Parse(deps dependencies) (reflect.Value, error) {
-	m := deps.match(d)
+func (d *dependency) Parse(r *resolution) (reflect.Value, error) {
+	m := r.match(d)
 	if m == nil {
-		return reflect.Value{}, d.notExistError("")
+		if d.Optional {
+			return reflect.Zero(d.Type), nil
+		}
+		return reflect.Value{}, r.injector.matchError(d, "")
+	}
+	v, err := r.load(m)
+	if err != nil {
+		return reflect.Value{}, err
 	}
-	if !m.Val.IsValid() {
+	if !v.IsValid() {
 		return reflect.Value{}, d.notInitializedError("")
 	}
-	return m.Val, nil
+	r.injector.event(Event{Kind: EventResolve, Provider: m.Provider.name, Dependency: d.String()})
+	return v, nil
 }
 
-func (d *dependency) Resolve(deps dependencies, v reflect.Value) error {
-	m := deps.match(d)
+func (d *dependency) Resolve(r *resolution, v reflect.Value) error {
+	if d.replaced {
+		return nil
+	}
+	if d.Group != "" {
+		return r.store(d, v)
+	}
+	m := r.match(d)
 	if m == nil {
-		return d.notExistError("")
+		return r.injector.matchError(d, "")
 	}
-	m.Val = v
-	return nil
+	if m == d && len(m.decorators) > 0 {
+		// the value before decorating is kept to be stopped by the provider.
+		m.origin = v
+	}
+	return r.store(m, v)
 }
 
-func (d *dependency) Inject(v reflect.Value, deps dependencies) error {
-	m := deps.match(d)
-	if m == nil {
-		return d.notExistError("")
-	}
-	if !m.Val.IsValid() {
-		return d.notInitializedError("")
+func (d *dependency) Inject(v reflect.Value, r *resolution) error {
+	val, err := d.Parse(r)
+	if err != nil {
+		return err
 	}
-	v.Set(m.Val)
+	v.Set(val)
 	return nil
 }
 
 type structureField struct {
 	fieldIndex int
 	*dependency
+	tool depTool
 }
 
 type structure struct {
@@ -126,10 +203,10 @@ type structure struct {
 	fields []structureField
 }
 
-func (s *structure) Parse(deps dependencies) (reflect.Value, error) {
+func (s *structure) Parse(r *resolution) (reflect.Value, error) {
 	v := reflect.New(s.Type).Elem()
 	for _, d := range s.fields {
-		fv, err := d.Parse(deps)
+		fv, err := d.tool.Parse(r)
 		if err != nil {
 			return reflect.Value{}, err
 		}
@@ -138,9 +215,9 @@ func (s *structure) Parse(deps dependencies) (reflect.Value, error) {
 	return v, nil
 }
 
-func (s *structure) Resolve(deps dependencies, v reflect.Value) error {
+func (s *structure) Resolve(r *resolution, v reflect.Value) error {
 	for _, d := range s.fields {
-		err := d.Resolve(deps, v.Field(d.fieldIndex))
+		err := d.tool.Resolve(r, v.Field(d.fieldIndex))
 		if err != nil {
 			return err
 		}
@@ -148,9 +225,9 @@ func (s *structure) Resolve(deps dependencies, v reflect.Value) error {
 	return nil
 }
 
-func (s *structure) Inject(v reflect.Value, deps dependencies) error {
+func (s *structure) Inject(v reflect.Value, r *resolution) error {
 	for _, d := range s.fields {
-		err := d.Inject(v.Field(d.fieldIndex), deps)
+		err := d.tool.Inject(v.Field(d.fieldIndex), r)
 		if err != nil {
 			return err
 		}
@@ -183,6 +260,14 @@ type optionValue struct {
 	MethodsPattern string
 	FuncObj        bool
 	Type           reflect.Type
+	Lifetime       lifetime
+	Group          string
+	Timeout        time.Duration
+	Decorator      bool
+	Override       bool
+	Conditions     []func() bool
+	Profiles       []string
+	module         *module
 
 	Value reflect.Value
 }
@@ -244,3 +329,19 @@ func OptTyped(v interface{}, t reflect.Type) interface{} {
 	o.Type = t
 	return o
 }
+
+// OptTransient indicates that the function provider should be re-run for each resolution instead of once
+// in Run, each consumer and each Inject destination receives a new value.
+func OptTransient(v interface{}) interface{} {
+	o := parseOptionValue(v)
+	o.Lifetime = lifetimeTransient
+	return o
+}
+
+// OptScoped indicates that the function provider should be run at most once for each Scope, it's never
+// run in Run and only available for scoped and transient providers or Scope.Inject.
+func OptScoped(v interface{}) interface{} {
+	o := parseOptionValue(v)
+	o.Lifetime = lifetimeScoped
+	return o
+}
@@ -0,0 +1,127 @@
+package di
+
+import (
+	"fmt"
+	"strings"
+)
+
+// MissingDependencyError indicates that the dependency is not provided.
+type MissingDependencyError struct {
+	Dependency string
+	Provider   string
+}
+
+func (e *MissingDependencyError) Error() string {
+	if e.Provider == "" {
+		return fmt.Sprintf("dependency %s not found", e.Dependency)
+	}
+	return fmt.Sprintf("dependency %s not found for provider %s", e.Dependency, e.Provider)
+}
+
+// UninitializedError indicates that the dependency is provided but it's provider is not run yet.
+type UninitializedError struct {
+	Dependency string
+	Provider   string
+}
+
+func (e *UninitializedError) Error() string {
+	if e.Provider == "" {
+		return fmt.Sprintf("dependency %s not initialized", e.Dependency)
+	}
+	return fmt.Sprintf("dependency %s not initialized for provider %s", e.Dependency, e.Provider)
+}
+
+// AmbiguousError indicates that there are more than one candidates for the dependency when interface
+// binding is enabled.
+type AmbiguousError struct {
+	Dependency string
+	Provider   string
+	Candidates []string
+}
+
+func (e *AmbiguousError) Error() string {
+	candidates := strings.Join(e.Candidates, ", ")
+	if e.Provider == "" {
+		return fmt.Sprintf("dependency %s is ambiguous: %s", e.Dependency, candidates)
+	}
+	return fmt.Sprintf("dependency %s is ambiguous for provider %s: %s", e.Dependency, e.Provider, candidates)
+}
+
+// ConflictError indicates that the dependency is provided by more than one providers.
+type ConflictError struct {
+	Dependency string
+	Providers  []string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("provider conflicted: %s, %s", strings.Join(e.Providers, ", "), e.Dependency)
+}
+
+// CycleError indicates cycle dependencies, Path is the full path of providers from the provider firstly
+// visited, the last one appears in the path twice.
+type CycleError struct {
+	Path []string
+}
+
+func (e *CycleError) Error() string {
+	return fmt.Sprintf("cycle dependencies: %v", e.Path)
+}
+
+// ScopeError indicates that a scoped dependency is required outside of scope.
+type ScopeError struct {
+	Dependency string
+	Provider   string
+}
+
+func (e *ScopeError) Error() string {
+	if e.Provider == "" {
+		return fmt.Sprintf("scoped dependency %s must be resolved in a scope", e.Dependency)
+	}
+	return fmt.Sprintf("scoped dependency %s is not available for provider %s", e.Dependency, e.Provider)
+}
+
+// ProviderError wraps the error returned by or occurred in the provider.
+type ProviderError struct {
+	Provider string
+	Err      error
+}
+
+func (e *ProviderError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Provider, e.Err.Error())
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.Err
+}
+
+// PanicError indicates that the provider panics, Value is the recovered value and Stack is the full stack
+// trace of the goroutine.
+type PanicError struct {
+	Provider string
+	Value    interface{}
+	Stack    []byte
+}
+
+func (e *PanicError) Error() string {
+	return fmt.Sprintf("provider %s panic: %v\n%s", e.Provider, e.Value, e.Stack)
+}
+
+// ProviderErrors is a collection of provider errors, it's returned when more than one errors may be
+// occurred, such as running with AsyncRunner, Validate and Shutdown.
+type ProviderErrors []*ProviderError
+
+func (e ProviderErrors) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+func (e ProviderErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, err := range e {
+		errs = append(errs, err)
+	}
+	return errs
+}
@@ -0,0 +1,83 @@
+package di
+
+import (
+	"time"
+)
+
+// EventKind is the kind of Event.
+type EventKind int
+
+const (
+	// EventProvide is sent when a provider or decorator is registered.
+	EventProvide EventKind = iota + 1
+	// EventResolve is sent when a dependency value is resolved, Provider is the provider of the value.
+	EventResolve
+	// EventError is sent when a provider failed in Run.
+	EventError
+	// EventPanic is sent when a provider panics, Value is the recovered value.
+	EventPanic
+	// EventPending is sent when providers provided in running are registered for next cycle, Count is the
+	// number of them.
+	EventPending
+	// EventShutdown is sent when a provider is stopped in Shutdown, Err is set if it's failed.
+	EventShutdown
+)
+
+func (k EventKind) String() string {
+	switch k {
+	case EventProvide:
+		return "provide"
+	case EventResolve:
+		return "resolve"
+	case EventError:
+		return "error"
+	case EventPanic:
+		return "panic"
+	case EventPending:
+		return "pending"
+	case EventShutdown:
+		return "shutdown"
+	default:
+		return "unknown"
+	}
+}
+
+// Event describe what happened in the injector, only fields related to the kind are set.
+type Event struct {
+	Kind       EventKind
+	Time       time.Time
+	Provider   string
+	Provides   []string
+	Dependency string
+	Duration   time.Duration
+	Err        error
+	Value      interface{}
+	Count      int
+}
+
+// EventLogger is a Logger receives events besides Begin/End, events are sent only if the logger used by
+// the injector implements it. It should be safe for concurrent use.
+type EventLogger interface {
+	Logger
+	Event(e Event)
+}
+
+// newSlogLogger create the SlogLogger, it's nil if log/slog is not available before Go 1.21.
+var newSlogLogger func() Logger
+
+func (j *Injector) event(e Event) {
+	l, ok := j.logger.(EventLogger)
+	if !ok {
+		return
+	}
+	e.Time = time.Now()
+	l.Event(e)
+}
+
+func (p *provider) provideNames() []string {
+	names := make([]string, 0, len(p.provides))
+	for _, d := range p.provides {
+		names = append(names, d.String())
+	}
+	return names
+}
@@ -0,0 +1,152 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Source is implemented by Injector and Scope, it's used by the generic API to resolve dependencies.
+type Source interface {
+	Inject(v ...interface{}) error
+}
+
+// Option wraps the value like the OptXXX functions, it's used by the generic API.
+type Option func(v interface{}) interface{}
+
+// WithName returns an Option of OptNamed.
+func WithName(name string) Option {
+	return func(v interface{}) interface{} {
+		return OptNamed(name, v)
+	}
+}
+
+// WithGroup returns an Option of OptGroup.
+func WithGroup(group string) Option {
+	return func(v interface{}) interface{} {
+		return OptGroup(group, v)
+	}
+}
+
+// WithDecompose returns an Option of OptDecompose.
+func WithDecompose() Option {
+	return OptDecompose
+}
+
+// WithTransient returns an Option of OptTransient.
+func WithTransient() Option {
+	return OptTransient
+}
+
+// WithScoped returns an Option of OptScoped.
+func WithScoped() Option {
+	return OptScoped
+}
+
+func applyOptions(v interface{}, opts []Option) interface{} {
+	for _, opt := range opts {
+		v = opt(v)
+	}
+	return v
+}
+
+func typeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// Get resolve the dependency of type T from the injector or scope.
+func Get[T any](s Source, opts ...Option) (T, error) {
+	var t T
+	err := s.Inject(applyOptions(&t, opts))
+	return t, err
+}
+
+// MustGet is same as Get except that it panics if failed.
+func MustGet[T any](s Source, opts ...Option) T {
+	t, err := Get[T](s, opts...)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+// Named resolve the dependency of type T with the name from the injector or scope.
+func Named[T any](s Source, name string) (T, error) {
+	return Get[T](s, WithName(name))
+}
+
+// Provide provide the static value as type T, it's helpful for providing interface.
+func Provide[T any](j *Injector, v T, opts ...Option) error {
+	return j.Provide(applyOptions(OptTyped(v, typeOf[T]()), opts))
+}
+
+// ProvideFunc provide the function as a provider, the first non-error return value of the function is
+// provided as type T, it's helpful for providing interface by constructors return concrete types.
+// WithName names the provided value instead of the provider.
+func ProvideFunc[T any](j *Injector, fn interface{}, opts ...Option) error {
+	refv := reflect.ValueOf(fn)
+	if refv.Kind() != reflect.Func {
+		return fmt.Errorf("provider must be function: %s", refv.Type())
+	}
+	o := parseOptionValue(applyOptions(refv, opts))
+	wrapped, err := wrapFuncResult(refv, typeOf[T](), o.Name)
+	if err != nil {
+		return err
+	}
+	o.Name = functionName(refv)
+	o.Value = wrapped
+	return j.Provide(o)
+}
+
+// wrapFuncResult create a function with the same parameters as fn, and the first non-error result is
+// replaced by type t, the result is wrapped by an anonymous structure with the field tagged by the name
+// if it's not empty.
+func wrapFuncResult(fn reflect.Value, t reflect.Type, name string) (reflect.Value, error) {
+	rt := t
+	if name != "" {
+		rt = reflect.StructOf([]reflect.StructField{{
+			Name: "Value",
+			Type: t,
+			Tag:  reflect.StructTag(`dep:"` + name + `"`),
+		}})
+	}
+	ft := fn.Type()
+	var (
+		ins   = make([]reflect.Type, 0, ft.NumIn())
+		outs  = make([]reflect.Type, 0, ft.NumOut())
+		index = -1
+	)
+	for i := 0; i < ft.NumIn(); i++ {
+		ins = append(ins, ft.In(i))
+	}
+	for i := 0; i < ft.NumOut(); i++ {
+		out := ft.Out(i)
+		if index < 0 && out != errorReftype {
+			if !out.AssignableTo(t) {
+				return reflect.Value{}, fmt.Errorf("incompatible type: %s to %s", out, t)
+			}
+			index = i
+			out = rt
+		}
+		outs = append(outs, out)
+	}
+	if index < 0 {
+		return reflect.Value{}, fmt.Errorf("function provides nothing: %s", ft)
+	}
+	wt := reflect.FuncOf(ins, outs, ft.IsVariadic())
+	return reflect.MakeFunc(wt, func(in []reflect.Value) []reflect.Value {
+		var out []reflect.Value
+		if ft.IsVariadic() {
+			out = fn.CallSlice(in)
+		} else {
+			out = fn.Call(in)
+		}
+		v := reflect.New(rt).Elem()
+		if name != "" {
+			v.Field(0).Set(out[index])
+		} else {
+			v.Set(out[index])
+		}
+		out[index] = v
+		return out
+	}), nil
+}
@@ -1,3 +1,3 @@
 module github.com/cosiner/go-di
 
-go 1.4
+go 1.20
@@ -0,0 +1,244 @@
+package di
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Graph is a serializable model of the dependency graph, edges point from the provider of a dependency to
+// the consumer.
+type Graph struct {
+	Nodes []GraphNode `json:"nodes"`
+	Edges []GraphEdge `json:"edges"`
+}
+
+// GraphNode is a provider, or a missing dependency if Missing is true.
+type GraphNode struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Provides  []string `json:"provides,omitempty"`
+	Lifetime  string   `json:"lifetime,omitempty"`
+	Inherited bool     `json:"inherited,omitempty"`
+	Missing   bool     `json:"missing,omitempty"`
+}
+
+// GraphEdge is a dependency between providers, Unsatisfied means the dependency is missing or ambiguous,
+// Cyclic means the edge is a part of cycle dependencies.
+type GraphEdge struct {
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Dependency  string `json:"dependency"`
+	Lazy        bool   `json:"lazy,omitempty"`
+	Optional    bool   `json:"optional,omitempty"`
+	Unsatisfied bool   `json:"unsatisfied,omitempty"`
+	Cyclic      bool   `json:"cyclic,omitempty"`
+}
+
+type graphBuilder struct {
+	graph   Graph
+	ids     map[*provider]string
+	missing map[string]string
+}
+
+func (b *graphBuilder) node(p *provider, inherited bool) string {
+	if id, has := b.ids[p]; has {
+		return id
+	}
+	id := "p" + strconv.Itoa(len(b.ids))
+	b.ids[p] = id
+	n := GraphNode{
+		ID:        id,
+		Name:      p.name,
+		Lifetime:  p.lifetime.String(),
+		Inherited: inherited,
+	}
+	for _, d := range p.provides {
+		n.Provides = append(n.Provides, d.String())
+	}
+	b.graph.Nodes = append(b.graph.Nodes, n)
+	return id
+}
+
+func (b *graphBuilder) missingNode(dep *dependency) string {
+	name := dep.String()
+	if id, has := b.missing[name]; has {
+		return id
+	}
+	id := "m" + strconv.Itoa(len(b.missing))
+	b.missing[name] = id
+	b.graph.Nodes = append(b.graph.Nodes, GraphNode{
+		ID:      id,
+		Name:    name,
+		Missing: true,
+	})
+	return id
+}
+
+// Graph returns the dependency graph of the injector, providers of parent injectors required by the
+// injector are marked as inherited.
+func (j *Injector) Graph() *Graph {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	b := graphBuilder{
+		ids:     make(map[*provider]string),
+		missing: make(map[string]string),
+	}
+	type edge struct {
+		*GraphEdge
+		from, to *provider
+	}
+	var (
+		local   = make(map[*provider]bool)
+		parents = make(map[*provider][]*provider)
+		edges   []edge
+	)
+	for _, p := range j.providers {
+		local[p] = true
+		b.node(p, false)
+	}
+	for _, p := range j.providers {
+		to := b.ids[p]
+		addEdge := func(dep *dependency, from *provider) {
+			e := edge{
+				GraphEdge: &GraphEdge{
+					To:         to,
+					Dependency: dep.String(),
+					Lazy:       dep.Lazy,
+					Optional:   dep.Optional,
+				},
+				to: p,
+			}
+			if from == nil {
+				e.From = b.missingNode(dep)
+				e.Unsatisfied = true
+			} else {
+				e.from = from
+				e.From = b.node(from, !local[from])
+				if !dep.Lazy {
+					parents[p] = append(parents[p], from)
+				}
+			}
+			edges = append(edges, e)
+		}
+		for _, dep := range p.deps {
+			var mods []*dependency
+			switch {
+			case dep.Group != "":
+				mods = j.group(dep)
+			case dep.Map:
+				for _, m := range j.named(dep) {
+					mods = append(mods, m)
+				}
+			default:
+				if m := j.match(dep); m != nil {
+					mods = append(mods, m)
+				}
+			}
+			if len(mods) == 0 && !dep.Optional {
+				addEdge(dep, nil)
+			}
+			for _, m := range mods {
+				for _, from := range m.providers(p) {
+					addEdge(dep, from)
+				}
+			}
+		}
+	}
+
+	cyclic := make(map[*provider]int)
+	for i, cycle := range findCycles(j.providers, parents) {
+		for _, p := range cycle {
+			cyclic[p] = i + 1
+		}
+	}
+	for _, e := range edges {
+		if e.from != nil && !e.Lazy {
+			e.Cyclic = cyclic[e.from] != 0 && cyclic[e.from] == cyclic[e.to]
+		}
+		b.graph.Edges = append(b.graph.Edges, *e.GraphEdge)
+	}
+	return &b.graph
+}
+
+func (n *GraphNode) label() string {
+	if n.Missing {
+		return "missing: " + n.Name
+	}
+	lines := make([]string, 0, len(n.Provides)+1)
+	if n.Name != "" {
+		lines = append(lines, n.Name)
+	}
+	return strings.Join(append(lines, n.Provides...), "\n")
+}
+
+// WriteDOT write the graph in Graphviz DOT format.
+func (g *Graph) WriteDOT(w io.Writer) error {
+	var buf strings.Builder
+	buf.WriteString("digraph di {\n")
+	for i := range g.Nodes {
+		n := &g.Nodes[i]
+		attrs := "shape=box"
+		switch {
+		case n.Missing:
+			attrs += ", color=red, style=dashed"
+		case n.Inherited:
+			attrs += ", style=dashed"
+		}
+		fmt.Fprintf(&buf, "\t%s [label=%s, %s];\n", n.ID, strconv.Quote(n.label()), attrs)
+	}
+	for _, e := range g.Edges {
+		var attrs []string
+		attrs = append(attrs, "label="+strconv.Quote(e.Dependency))
+		if e.Unsatisfied || e.Cyclic {
+			attrs = append(attrs, "color=red")
+		}
+		if e.Lazy || e.Unsatisfied {
+			attrs = append(attrs, "style=dashed")
+		}
+		fmt.Fprintf(&buf, "\t%s -> %s [%s];\n", e.From, e.To, strings.Join(attrs, ", "))
+	}
+	buf.WriteString("}\n")
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+func mermaidEscape(s string) string {
+	s = strings.Replace(s, `"`, "#quot;", -1)
+	return strings.Replace(s, "\n", "<br/>", -1)
+}
+
+// WriteMermaid write the graph in Mermaid flowchart format.
+func (g *Graph) WriteMermaid(w io.Writer) error {
+	var buf strings.Builder
+	buf.WriteString("graph LR\n")
+	for i := range g.Nodes {
+		n := &g.Nodes[i]
+		fmt.Fprintf(&buf, "\t%s[\"%s\"]\n", n.ID, mermaidEscape(n.label()))
+		if n.Missing {
+			fmt.Fprintf(&buf, "\tstyle %s stroke:red,stroke-dasharray:5\n", n.ID)
+		}
+	}
+	for i, e := range g.Edges {
+		arrow := "-->"
+		if e.Lazy || e.Unsatisfied {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&buf, "\t%s %s|\"%s\"| %s\n", e.From, arrow, mermaidEscape(e.Dependency), e.To)
+		if e.Unsatisfied || e.Cyclic {
+			fmt.Fprintf(&buf, "\tlinkStyle %d stroke:red\n", i)
+		}
+	}
+	_, err := io.WriteString(w, buf.String())
+	return err
+}
+
+// WriteJSON write the graph in JSON format.
+func (g *Graph) WriteJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(g)
+}
@@ -0,0 +1,106 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// OptGroup indicates that values provided by the value/function are contributed to the named group instead
+// of being dependencies, a consumer receives all of them as a slice by a structure field tagged with
+// `dep:"group:name"`, after all contributors are finished.
+func OptGroup(group string, v interface{}) interface{} {
+	o := parseOptionValue(v)
+	o.Group = group
+	return o
+}
+
+// groupLocal find values of the group contributed to the injector itself, they must be assignable to
+// the element type of the slice.
+func (j *Injector) groupLocal(d *dependency) []*dependency {
+	var mods []*dependency
+	elem := d.Type.Elem()
+	for _, m := range j.groups[d.Group] {
+		if m.Type.AssignableTo(elem) {
+			mods = append(mods, m)
+		}
+	}
+	return mods
+}
+
+// group find values of the group contributed to the injector and the parent injectors, values of parent
+// injectors come first.
+func (j *Injector) group(d *dependency) []*dependency {
+	var mods []*dependency
+	if j.parent != nil {
+		mods = j.parent.group(d)
+	}
+	return append(mods, j.groupLocal(d)...)
+}
+
+// checkGroup report contributors of the group which are not assignable to the element type of the
+// consumer.
+func (j *Injector) checkGroup(d *dependency, errs *providerErrors) {
+	elem := d.Type.Elem()
+	for inj := j; inj != nil; inj = inj.parent {
+		for _, m := range inj.groups[d.Group] {
+			if !m.Type.AssignableTo(elem) {
+				errs.Append(m.Provider.name, fmt.Errorf("contributor %s of group %s is not assignable to %s", m.Type, d.Group, elem))
+			}
+		}
+	}
+}
+
+func (j *Injector) registerGroup(mod *dependency) {
+	if j.groups == nil {
+		j.groups = make(map[string][]*dependency)
+	}
+	j.groups[mod.Group] = append(j.groups[mod.Group], mod)
+}
+
+type groupParser struct {
+	dep *dependency
+}
+
+func (g groupParser) Parse(r *resolution) (reflect.Value, error) {
+	mods := r.injector.group(g.dep)
+	if len(mods) == 0 && !g.dep.Optional {
+		return reflect.Value{}, g.dep.notExistError("")
+	}
+	v := reflect.MakeSlice(g.dep.Type, 0, len(mods))
+	for _, m := range mods {
+		val, err := r.load(m)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if !val.IsValid() {
+			return reflect.Value{}, m.notInitializedError("")
+		}
+		v = reflect.Append(v, val)
+	}
+	return v, nil
+}
+
+func (g groupParser) Resolve(r *resolution, v reflect.Value) error {
+	return nil
+}
+
+func (g groupParser) Inject(v reflect.Value, r *resolution) error {
+	val, err := g.Parse(r)
+	if err != nil {
+		return err
+	}
+	v.Set(val)
+	return nil
+}
+
+func newGroupParser(t reflect.Type, tag fieldTag) (*dependency, depTool, error) {
+	if t.Kind() != reflect.Slice {
+		return nil, nil, fmt.Errorf("group %s must be received by slice: %s", tag.Group, t.String())
+	}
+	d := &dependency{
+		Type:     t,
+		Group:    tag.Group,
+		Optional: tag.Optional,
+	}
+	return d, groupParser{dep: d}, nil
+}
@@ -2,11 +2,14 @@
 package di
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
 	"reflect"
 	"regexp"
+	"runtime/debug"
+	"strings"
 	"sync"
 	"sync/atomic"
 	"time"
@@ -16,18 +19,24 @@ var errorReftype = reflect.TypeOf((*error)(nil)).Elem()
 
 // Injector implements the dependency injection.
 type Injector struct {
-	running uint32
+	running          uint32
+	parent           *Injector
+	interfaceBinding bool
 
 	mu        sync.RWMutex
 	providers []*provider
 	deps      dependencies
+	groups    map[string][]*dependency
 
 	pendingMu        sync.Mutex
 	pendingProviders []interface{}
+	provided         []interface{}
 
-	runner Runner
-	logger Logger
-	dones  providerDones
+	runner   func() Runner
+	logger   Logger
+	profiles []string
+	dones    providerDones
+	locks    providerLocks
 }
 
 // New create a injector instance.
@@ -37,20 +46,56 @@ func New() *Injector {
 	}
 }
 
+// Child create a child injector, dependencies not found in the child will be resolved from the injector.
+// Providers of the child may depend on values of the injector without re-running them, so the injector
+// should be run before the child. The child inherits the runner and logger.
+func (j *Injector) Child() *Injector {
+	return &Injector{
+		parent:           j,
+		deps:             make(dependencies),
+		runner:           j.runner,
+		logger:           j.logger,
+		profiles:         j.profiles,
+		interfaceBinding: j.interfaceBinding,
+	}
+}
+
 func NewAndParseEnv(prefix string) *Injector {
 	inj := New()
 
 	if os.Getenv(prefix+"DI_SYNC") == "true" {
 		inj.UseRunner(syncRunner{})
 	}
-	if os.Getenv(prefix+"DI_LOG") == "true" {
+	switch os.Getenv(prefix + "DI_LOG") {
+	case "true":
 		inj.UseLogger(DefaultLogger{})
+	case "slog":
+		if newSlogLogger != nil {
+			inj.UseLogger(newSlogLogger())
+		}
+	}
+	if profiles := os.Getenv(prefix + "DI_PROFILES"); profiles != "" {
+		inj.UseProfiles(parseProfiles(profiles)...)
 	}
 	return inj
 }
 
+// UseRunner set the runner used by Run, built-in runners are re-created for each Run, so they are not
+// shared by child injectors and clones. Custom runners are shared, use UseRunnerFunc if they are stateful.
 func (j *Injector) UseRunner(r Runner) *Injector {
-	j.runner = r
+	if f, ok := r.(runnerFactory); ok {
+		j.runner = f.newRunner
+	} else {
+		j.runner = func() Runner {
+			return r
+		}
+	}
+	return j
+}
+
+// UseRunnerFunc set the function to create runner for each Run.
+func (j *Injector) UseRunnerFunc(fn func() Runner) *Injector {
+	j.runner = fn
 	return j
 }
 
@@ -59,7 +104,15 @@ func (j *Injector) UseLogger(l Logger) *Injector {
 	return j
 }
 
-func (j *Injector) analyseStructure(t reflect.Type, provider *provider) ([]*dependency, *structure) {
+// UseInterfaceBinding enable or disable interface binding, when it's enabled, a dependency of interface
+// type not provided exactly will be satisfied by the unique provided value whose type implements it.
+// Values have the same name are preferred, and it's an error if there are more than one candidates.
+func (j *Injector) UseInterfaceBinding(enable bool) *Injector {
+	j.interfaceBinding = enable
+	return j
+}
+
+func (j *Injector) analyseStructure(t reflect.Type, provider *provider) ([]*dependency, *structure, error) {
 	s := &structure{
 		Type: t,
 	}
@@ -72,25 +125,110 @@ func (j *Injector) analyseStructure(t reflect.Type, provider *provider) ([]*depe
 		if tag == "-" {
 			continue
 		}
-		n := tag
-		if n == "" {
-			n = ft.Name
+		ftag := parseFieldTag(tag)
+		if ftag.Name == "" && ftag.Group == "" {
+			ftag.Name = ft.Name
 		}
-		if n == "" {
+		if ftag.Name == "" && ftag.Group == "" {
 			continue
 		}
-		d := &dependency{
-			Type:     ft.Type,
-			Var:      n,
-			Provider: provider,
+		var (
+			d    *dependency
+			tool depTool
+			err  error
+		)
+		if provider == nil {
+			d, tool, err = j.analyseDependency(ft.Type, ftag)
+			if err != nil {
+				return nil, nil, err
+			}
+		} else {
+			d = &dependency{
+				Type:     ft.Type,
+				Var:      ftag.Name,
+				Group:    ftag.Group,
+				Provider: provider,
+			}
+			tool = d
 		}
 		deps = append(deps, d)
 		s.fields = append(s.fields, structureField{
 			fieldIndex: i,
 			dependency: d,
+			tool:       tool,
 		})
 	}
-	return deps, s
+	return deps, s, nil
+}
+
+type fieldTag struct {
+	Name     string
+	Group    string
+	Optional bool
+	Map      bool
+}
+
+// parseFieldTag parse the "dep" tag of structure field, the format is "name,option..." or
+// "group:name,option...", available options: optional, map.
+// The map option indicates that the field of type map[string]T receives all named dependencies of type T.
+func parseFieldTag(tag string) fieldTag {
+	var ftag fieldTag
+	for i, s := range strings.Split(tag, ",") {
+		s = strings.TrimSpace(s)
+		if i == 0 {
+			if strings.HasPrefix(s, "group:") {
+				ftag.Group = strings.TrimPrefix(s, "group:")
+			} else {
+				ftag.Name = s
+			}
+			continue
+		}
+		switch s {
+		case "optional":
+			ftag.Optional = true
+		case "map":
+			ftag.Map = true
+		}
+	}
+	return ftag
+}
+
+// analyseDependency analyse the dependency of a function parameter, structure field or Inject destination.
+func (j *Injector) analyseDependency(t reflect.Type, tag fieldTag) (*dependency, depTool, error) {
+	if tag.Group != "" {
+		return newGroupParser(t, tag)
+	}
+	if tag.Map {
+		return newNamedMapParser(t, tag)
+	}
+	if t.Implements(lazyReftype) {
+		if t.Kind() != reflect.Struct {
+			return nil, nil, fmt.Errorf("lazy dependency must be Lazy[T] instead of pointer: %s", t)
+		}
+		d := &dependency{
+			Type: reflect.Zero(t).Interface().(lazyType).lazyElem(),
+			Var:  tag.Name,
+			Lazy: true,
+		}
+		return d, lazyParser{dep: d, typ: t}, nil
+	}
+	if t.Implements(optionalReftype) {
+		if t.Kind() != reflect.Struct {
+			return nil, nil, fmt.Errorf("optional dependency must be Optional[T] instead of pointer: %s", t)
+		}
+		d := &dependency{
+			Type:     reflect.Zero(t).Interface().(optionalType).optionalElem(),
+			Var:      tag.Name,
+			Optional: true,
+		}
+		return d, optionalParser{dep: d, typ: t}, nil
+	}
+	d := &dependency{
+		Type:     t,
+		Var:      tag.Name,
+		Optional: tag.Optional,
+	}
+	return d, d, nil
 }
 
 func (j *Injector) analyseFunc(name string, t reflect.Type, v reflect.Value) (*provider, error) {
@@ -106,14 +244,24 @@ func (j *Injector) analyseFunc(name string, t reflect.Type, v reflect.Value) (*p
 	p.deps = make([]*dependency, 0, l)
 	for i := 0; i < l; i++ {
 		in := t.In(i)
-		if in.Kind() == reflect.Struct && in.Name() == "" {
-			ds, parser := j.analyseStructure(in, nil)
+		if in == lifecycleReftype {
+			p.depParsers = append(p.depParsers, lifecycleParser{provider: &p})
+		} else if in == contextReftype {
+			p.depParsers = append(p.depParsers, contextParser{})
+		} else if in.Kind() == reflect.Struct && in.Name() == "" {
+			ds, parser, err := j.analyseStructure(in, nil)
+			if err != nil {
+				return nil, err
+			}
 			p.deps = append(p.deps, ds...)
 			p.depParsers = append(p.depParsers, parser)
 		} else {
-			d := &dependency{Type: in}
+			d, parser, err := j.analyseDependency(in, fieldTag{})
+			if err != nil {
+				return nil, err
+			}
 			p.deps = append(p.deps, d)
-			p.depParsers = append(p.depParsers, d)
+			p.depParsers = append(p.depParsers, parser)
 		}
 	}
 
@@ -122,7 +270,10 @@ func (j *Injector) analyseFunc(name string, t reflect.Type, v reflect.Value) (*p
 	for i := 0; i < l; i++ {
 		out := t.Out(i)
 		if out.Kind() == reflect.Struct && out.Name() == "" {
-			ds, resolver := j.analyseStructure(out, &p)
+			ds, resolver, err := j.analyseStructure(out, &p)
+			if err != nil {
+				return nil, err
+			}
 			for _, d := range ds {
 				p.provides = append(p.provides, d)
 			}
@@ -160,9 +311,19 @@ func (j *Injector) analyseProvider(opt optionValue) (*provider, error) {
 		if err != nil {
 			return nil, err
 		}
+		if opt.Lifetime != lifetimeSingleton && fp.hasLifecycle() {
+			return nil, fmt.Errorf("lifecycle is not available for %s provider: %s", opt.Lifetime, fp.name)
+		}
 		p = fp
+		p.lifetime = opt.Lifetime
+		p.timeout = opt.Timeout
+	case opt.Lifetime != lifetimeSingleton:
+		return nil, fmt.Errorf("only function provider could be %s: %s", opt.Lifetime, t.String())
 	case k == reflect.Struct && (opt.Decomposable || t.Name() == ""):
-		ds, resolver := j.analyseStructure(t, p)
+		ds, resolver, err := j.analyseStructure(t, p)
+		if err != nil {
+			return nil, err
+		}
 		for i, d := range ds {
 			d.Val = v.Field(resolver.fields[i].fieldIndex)
 		}
@@ -175,68 +336,148 @@ func (j *Injector) analyseProvider(opt optionValue) (*provider, error) {
 			Provider: p,
 		})
 	}
+	if opt.Group != "" {
+		for _, d := range p.provides {
+			d.Group = opt.Group
+		}
+	}
 	return p, nil
 }
 
+// owns checks whether the provider is registered to the injector itself.
+func (j *Injector) owns(p *provider) bool {
+	for _, prov := range j.providers {
+		if prov == p {
+			return true
+		}
+	}
+	return false
+}
+
+func (j *Injector) implementations(d *dependency) []*dependency {
+	if !j.interfaceBinding || d.Type.Kind() != reflect.Interface {
+		return nil
+	}
+	return j.deps.implementations(d)
+}
+
+// matchLocal find the dependency from the injector itself, interface binding is applied if it's enabled.
+func (j *Injector) matchLocal(d *dependency) *dependency {
+	if m := j.deps.match(d); m != nil {
+		return m
+	}
+	if impls := j.implementations(d); len(impls) == 1 {
+		return impls[0]
+	}
+	return nil
+}
+
+// match find the dependency from the injector, and fallback to the parent injectors.
+func (j *Injector) match(d *dependency) *dependency {
+	for ; j != nil; j = j.parent {
+		if m := j.matchLocal(d); m != nil {
+			return m
+		}
+	}
+	return nil
+}
+
+// matchError returns the error for the dependency can't be matched.
+func (j *Injector) matchError(d *dependency, provider string) error {
+	for ; j != nil; j = j.parent {
+		if impls := j.implementations(d); len(impls) > 1 {
+			return d.ambiguousError(provider, impls)
+		}
+	}
+	return d.notExistError(provider)
+}
+
 func (j *Injector) hasConflict(mods []*dependency, mod *dependency) (string, bool) {
 	for _, m := range mods {
-		if mod.Var == m.Var {
+		if m.sameAs(mod) {
 			return m.Provider.name, true
 		}
 	}
 	return "", false
 }
 
-func (j *Injector) registerProvider(p *provider) error {
+func (j *Injector) registerProvider(p *provider, override bool) error {
 	for i := range p.provides {
 		mod := p.provides[i]
+		if mod.Group != "" {
+			j.registerGroup(mod)
+			continue
+		}
 		mods := j.deps[mod.Type]
-		if name, conflicted := j.hasConflict(mods, mod); conflicted {
-			return fmt.Errorf("provider conflicted: %s, %s, %s", name, p.name, mod.Type.String())
+		if override {
+			mods = j.replaceDependency(mods, mod)
+		} else if name, conflicted := j.hasConflict(mods, mod); conflicted {
+			return &ConflictError{
+				Dependency: mod.Type.String(),
+				Providers:  []string{name, p.name},
+			}
+		} else {
+			mods = append(mods, mod)
 		}
-		mods = append(mods, mod)
 		if j.deps == nil {
 			j.deps = make(dependencies)
 		}
 		j.deps[mod.Type] = mods
 	}
 	j.providers = append(j.providers, p)
+	j.event(Event{Kind: EventProvide, Provider: p.name, Provides: p.provideNames()})
 	return nil
 }
 
 func (j *Injector) provideVal(v optionValue) error {
+	if v.Decorator {
+		return j.decorate(v)
+	}
 	p, err := j.analyseProvider(v)
 	if err != nil {
 		return err
 	}
-	return j.registerProvider(p)
+	if v.module != nil {
+		v.module.apply(p)
+	}
+	return j.registerProvider(p, v.Override)
+}
+
+func (j *Injector) provideOption(o optionValue) error {
+	if !j.enabled(o) {
+		return nil
+	}
+	if spec, ok := o.moduleSpec(); ok {
+		return j.provideModule(spec, o.module)
+	}
+	if o.MethodsPattern == "" {
+		return j.provideVal(o)
+	}
+	methods, err := j.parseMethods(o.Value, o.MethodsPattern, o)
+	if err != nil {
+		return err
+	}
+	for _, m := range methods {
+		err = j.provideVal(m)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (j *Injector) provide(v ...interface{}) error {
 	for _, arg := range v {
-		o := parseOptionValue(arg)
-		if o.MethodsPattern != "" {
-			methods, err := j.parseMethods(o.Value, o.MethodsPattern)
-			if err != nil {
-				return err
-			}
-			for _, m := range methods {
-				err = j.provideVal(m)
-				if err != nil {
-					return err
-				}
-			}
-		} else {
-			err := j.provideVal(o)
-			if err != nil {
-				return err
-			}
+		err := j.provideOption(parseOptionValue(arg))
+		if err != nil {
+			return err
 		}
+		j.provided = append(j.provided, arg)
 	}
 	return nil
 }
 
-func (j *Injector) parseMethods(refv reflect.Value, pattern string) ([]optionValue, error) {
+func (j *Injector) parseMethods(refv reflect.Value, pattern string, opt optionValue) ([]optionValue, error) {
 	matcher, err := regexp.Compile(pattern)
 	if err != nil {
 		return nil, err
@@ -250,8 +491,14 @@ func (j *Injector) parseMethods(refv reflect.Value, pattern string) ([]optionVal
 		m := reft.Method(i)
 		if matcher.MatchString(m.Name) {
 			providers = append(providers, optionValue{
-				Name:  functionName(m.Func),
-				Value: refv.Method(i),
+				Name:      functionName(m.Func),
+				Value:     refv.Method(i),
+				Lifetime:  opt.Lifetime,
+				Group:     opt.Group,
+				Timeout:   opt.Timeout,
+				Decorator: opt.Decorator,
+				Override:  opt.Override,
+				module:    opt.module,
 			})
 		}
 	}
@@ -274,9 +521,15 @@ func (j *Injector) clearPendingProviders(v []interface{}) []interface{} {
 //
 // * Function is runnable provider, it depends on parameters and provide return values, empty parameters or providers is
 // allowed. Parameters and return values follow the same rules with static value. And function can return at most one error to indicate
-// the runtime error.
+// the runtime error. A parameter of type Lifecycle is not a dependency, it's used to register start/stop hooks of the provider,
+// and a parameter of type context.Context receives the context passed to RunContext.
+// A dependency wrapped by Optional or a structure field tagged with `dep:"name,optional"` is not required, it will
+// be the zero value if not provided. A dependency wrapped by Lazy is resolved at the first call of it's Get method.
+//
+// * Module created by Module groups providers, see Module for details.
 //
-// Available option functions: all of OptDecompose, OptNamed, OptMethods, OptFuncObj.
+// Available option functions: all of OptDecompose, OptNamed, OptMethods, OptFuncObj, OptTyped, OptTransient, OptScoped,
+// OptGroup, OptTimeout, OptOverride, OptWhen, OptProfile.
 func (j *Injector) Provide(v ...interface{}) error {
 	if atomic.LoadUint32(&j.running) == 0 {
 		j.mu.Lock()
@@ -290,26 +543,50 @@ func (j *Injector) Provide(v ...interface{}) error {
 	return nil
 }
 
-func (j *Injector) runProvider(p *provider) error {
+func (j *Injector) runProvider(p *provider, r *resolution) error {
 	if !p.fn.IsValid() {
 		return nil
 	}
+	if p.timeout > 0 {
+		return j.runProviderTimeout(p, r)
+	}
+	return j.callProvider(p, r)
+}
+
+// recoverProvider convert the panic to PanicError, it must be deferred directly.
+func recoverProvider(p *provider, err *error) {
+	if e := recover(); e != nil {
+		*err = &PanicError{Provider: p.name, Value: e, Stack: debug.Stack()}
+	}
+}
+
+func (j *Injector) callProvider(p *provider, r *resolution) error {
+	out, err := j.callFunc(p, r)
+	if err != nil {
+		return err
+	}
+	return j.resolveOutputs(p, r, out)
+}
+
+// callFunc call the function of provider with resolved dependencies, outputs are returned without
+// resolving.
+func (j *Injector) callFunc(p *provider, r *resolution) (out []reflect.Value, err error) {
+	defer recoverProvider(p, &err)
 
 	in := make([]reflect.Value, 0, len(p.depParsers))
 	for _, dp := range p.depParsers {
-		v, err := dp.Parse(j.deps)
+		v, err := dp.Parse(r)
 		if err != nil {
-			return err
+			return nil, err
 		}
 		in = append(in, v)
 	}
-	out := p.fn.Call(in)
-	out, reterr := p.errorResolver.Resolve(out)
-	if reterr != nil {
-		return reterr
-	}
+	return p.errorResolver.Resolve(p.fn.Call(in))
+}
+
+func (j *Injector) resolveOutputs(p *provider, r *resolution, out []reflect.Value) error {
 	for i := range out {
-		err := p.provideResolvers[i].Resolve(j.deps, out[i])
+		err := p.provideResolvers[i].Resolve(r, out[i])
 		if err != nil {
 			return err
 		}
@@ -317,33 +594,101 @@ func (j *Injector) runProvider(p *provider) error {
 	return nil
 }
 
+// execute run the singleton provider and it's start hooks if it's not done, it's safe to be called
+// concurrently.
+func (j *Injector) execute(p *provider, r *resolution) error {
+	err := j.locks.lock(p, r.exec)
+	if err != nil {
+		return err
+	}
+	defer j.locks.unlock(p)
+	if j.dones.isDone(p) {
+		return nil
+	}
+
+	logger := j.logger
+	if logger == nil {
+		logger = nopLogger{}
+	}
+	begin := time.Now()
+	if p.name != "" {
+		logger.Begin(p.name, begin)
+	}
+	err = j.runProvider(p, r)
+	if err == nil {
+		err = j.startProvider(r.context(), p)
+	}
+	if err != nil {
+		e := Event{Kind: EventError, Provider: p.name, Err: err, Duration: time.Since(begin)}
+		var perr *PanicError
+		if errors.As(err, &perr) {
+			e.Kind, e.Value = EventPanic, perr.Value
+		}
+		j.event(e)
+		return err
+	}
+	end := time.Now()
+	if p.name != "" {
+		logger.End(p.name, end, end.Sub(begin))
+	}
+	p.timing.begin, p.timing.end = begin, end
+	j.dones.markDone(p)
+	return nil
+}
+
 func (j *Injector) checkAllDeps() error {
 	var errs providerErrors
+	j.checkDeps(&errs)
+	return errs.ToError()
+}
+
+func (j *Injector) checkDeps(errs *providerErrors) {
 	for _, p := range j.providers {
 		for _, dep := range p.deps {
-			if j.deps.match(dep) == nil {
-				errs.Append(p.name, fmt.Errorf("dependency not found: %s", dep.String()))
+			if dep.Group != "" {
+				if !dep.Optional && len(j.group(dep)) == 0 {
+					errs.Append(p.name, dep.notExistError(""))
+				}
+				j.checkGroup(dep, errs)
+				continue
+			}
+			if dep.Map {
+				if !dep.Optional && len(j.named(dep)) == 0 {
+					errs.Append(p.name, dep.notExistError(""))
+				}
+				continue
+			}
+			if !dep.Optional && j.match(dep) == nil {
+				errs.Append(p.name, j.matchError(dep, ""))
 			}
 		}
 	}
-	return errs.ToError()
 }
 
 // Run build a priority queue by the dependency graph, and execute each provider function, the error will
 // be returned for any providers.
 // Before it finished, all new providers will be marked as pending state, and be execute in next cycle.
 func (j *Injector) Run() error {
+	return j.RunContext(context.Background())
+}
+
+// RunContext is same as Run, the context is passed to providers declare a context.Context parameter and
+// start hooks. Once the context is canceled, no more providers will be scheduled, and the error of the
+// context is returned if there are providers not finished.
+func (j *Injector) RunContext(ctx context.Context) error {
+	return j.run(ctx, nil)
+}
+
+// run run all providers, or only providers required by the target if it's not nil, the target itself is
+// never run.
+func (j *Injector) run(ctx context.Context, target *provider) error {
 	if !atomic.CompareAndSwapUint32(&j.running, 0, 1) {
 		return errors.New("dependencies is already running")
 	}
 
-	runner := j.runner
-	if runner == nil {
-		runner = SyncRunner()
-	}
-	logger := j.logger
-	if logger == nil {
-		logger = nopLogger{}
+	runner := SyncRunner()
+	if j.runner != nil {
+		runner = j.runner()
 	}
 	j.mu.Lock()
 	defer func() {
@@ -352,46 +697,58 @@ func (j *Injector) Run() error {
 	}()
 
 	for {
-		err := j.checkAllDeps()
-		if err != nil {
-			return err
+		var (
+			queue []*queueNode
+			err   error
+		)
+		if target == nil {
+			err = j.checkAllDeps()
+			if err != nil {
+				return err
+			}
+			queue, err = newQueue(j, j.providers, &j.dones)
+		} else {
+			queue, err = newQueue(j, []*provider{target}, &j.dones)
+			queue = removeNode(queue, target)
 		}
-
-		queue, err := newQueue(j.providers, j.deps, &j.dones)
 		if err != nil {
 			return err
 		}
 
-		for _, n := range queue {
-			p := n.provider
-			err = runner.run(j, p, func() error {
-				begin := time.Now()
-				if p.name != "" {
-					logger.Begin(p.name, begin)
-				}
-				err = j.runProvider(p)
-				if err == nil {
-					end := time.Now()
-					if p.name != "" {
-						logger.End(p.name, end, end.Sub(begin))
-					}
-					j.dones.markDone(p)
-				}
-				return err
-			})
+		tasks := newTasks(ctx, queue, func(t *task) error {
+			r := j.newResolution(nil)
+			r.ctx = ctx
+			r.running = []*provider{t.node.provider}
+			return j.execute(t.node.provider, r)
+		})
+		for _, t := range tasks {
+			if ctx.Err() != nil {
+				break
+			}
+			t.node.provider.timing.schedule(t.node.parents)
+			err = runner.Schedule(ctx, t)
 			if err != nil {
+				runner.Wait()
 				return err
 			}
 		}
-		err = runner.waitDone()
+		err = runner.Wait()
 		if err != nil {
 			return err
 		}
+		if err = ctx.Err(); err != nil {
+			for _, n := range queue {
+				if !j.dones.isDone(n.provider) {
+					return err
+				}
+			}
+		}
 
 		providers := j.clearPendingProviders(nil)
 		if len(providers) == 0 {
 			break
 		}
+		j.event(Event{Kind: EventPending, Count: len(providers)})
 		err = j.provide(providers...)
 		if err != nil {
 			return err
@@ -400,25 +757,27 @@ func (j *Injector) Run() error {
 	return nil
 }
 
-func (j *Injector) inject(v interface{}) error {
+func (j *Injector) inject(v interface{}, r *resolution) error {
 	o := parseOptionValue(v)
 	if o.Value.Kind() != reflect.Ptr {
 		return fmt.Errorf("destination must be pointer")
 	}
 	o.Value = o.Value.Elem()
-	dep := dependency{
-		Type: o.Value.Type(),
-		Var:  o.Name,
+	dep, tool, err := j.analyseDependency(o.Value.Type(), fieldTag{Name: o.Name, Group: o.Group})
+	if err != nil {
+		return err
 	}
-	mod := j.deps.match(&dep)
-	if mod != nil {
-		return dep.Inject(o.Value, j.deps)
+	if dep.Optional || dep.Group != "" || dep.Map || j.match(dep) != nil {
+		return tool.Inject(o.Value, r)
 	}
 	if o.Value.Kind() != reflect.Struct || (dep.Type.Name() != "" && !o.Decomposable) {
-		return dep.notExistError("")
+		return j.matchError(dep, "")
+	}
+	_, s, err := j.analyseStructure(dep.Type, nil)
+	if err != nil {
+		return err
 	}
-	_, r := j.analyseStructure(dep.Type, nil)
-	return r.Inject(o.Value, j.deps)
+	return s.Inject(o.Value, r)
 }
 
 // Inject inject all resolved dependency values to destination pointers, it should be called
@@ -428,7 +787,7 @@ func (j *Injector) Inject(v ...interface{}) error {
 	j.mu.RLock()
 	defer j.mu.RUnlock()
 	for _, p := range v {
-		err := j.inject(p)
+		err := j.inject(p, j.newResolution(nil))
 		if err != nil {
 			return err
 		}
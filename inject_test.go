@@ -1,12 +1,21 @@
 package di
 
 import (
+	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
+	"fmt"
+	"io"
 	"log"
 	"os"
 	"reflect"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 )
 
 func TestDI(t *testing.T) {
@@ -283,3 +292,1502 @@ func TestFuncObj(t *testing.T) {
 		t.Fatal()
 	}
 }
+
+type closer struct {
+	name   string
+	closed *[]string
+}
+
+func (c closer) Close() error {
+	*c.closed = append(*c.closed, c.name)
+	return nil
+}
+
+func TestLifecycle(t *testing.T) {
+	type DB struct{ closer }
+	type Server struct{ closer }
+
+	var events []string
+	d := New()
+	err := d.Provide(
+		func() *DB {
+			return &DB{closer{name: "db", closed: &events}}
+		},
+		func(db *DB, lc Lifecycle) *Server {
+			lc.Append(Hook{
+				OnStart: func(context.Context) error {
+					events = append(events, "start")
+					return nil
+				},
+				OnStop: func(context.Context) error {
+					events = append(events, "stop")
+					return errors.New("STOP")
+				},
+			})
+			return &Server{closer{name: "server", closed: &events}}
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.Shutdown(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "STOP") {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(events, []string{"start", "stop", "server", "db"}) {
+		t.Fatal(events)
+	}
+	err = d.Shutdown(context.Background())
+	if err != nil || len(events) != 4 {
+		t.Fatal(err, events)
+	}
+
+	type Store interface{ io.Closer }
+	var closed []string
+	d = New()
+	d.Provide(
+		func() *DB { return &DB{closer{name: "db", closed: &closed}} },
+		func(db *DB) Store { return db },
+	)
+	if err = d.Run(); err != nil {
+		t.Fatal(err)
+	}
+	err = d.Shutdown(context.Background())
+	if err != nil || !reflect.DeepEqual(closed, []string{"db"}) {
+		t.Fatal(err, closed)
+	}
+}
+
+func TestScope(t *testing.T) {
+	type Config struct{ Name string }
+	type Request struct{ ID int }
+	type Logger struct {
+		Config  *Config
+		Request *Request
+	}
+
+	var (
+		requests int
+		loggers  int
+	)
+	d := New()
+	err := d.Provide(
+		&Config{Name: "app"},
+		OptScoped(func() *Request {
+			requests++
+			return &Request{ID: requests}
+		}),
+		OptTransient(func(c *Config, r *Request) *Logger {
+			loggers++
+			return &Logger{Config: c, Request: r}
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var l1, l2 *Logger
+	if err = d.Inject(&l1); err == nil || !strings.Contains(err.Error(), "scope") {
+		t.Fatal(err)
+	}
+
+	s1 := d.NewScope()
+	err = s1.Inject(&l1, &l2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l1 == l2 || l1.Request != l2.Request || l1.Config != l2.Config || l1.Request.ID != 1 {
+		t.Fatal()
+	}
+	s2 := d.NewScope()
+	err = s2.Inject(&l2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if l2.Request.ID != 2 || l2.Config != l1.Config || requests != 2 || loggers != 3 {
+		t.Fatal(requests, loggers)
+	}
+
+	d = New()
+	d.Provide(
+		OptScoped(func() *Request { return &Request{} }),
+		func(r *Request) int { return r.ID },
+	)
+	err = d.Run()
+	if err == nil || !strings.Contains(err.Error(), "scoped dependency") {
+		t.Fatal(err)
+	}
+
+	type Tx struct{ closer }
+	type Session struct{ closer }
+	var closed []string
+	d = New()
+	d.Provide(
+		OptScoped(func() *Tx { return &Tx{closer{name: "tx", closed: &closed}} }),
+		OptScoped(func(*Tx) *Session { return &Session{closer{name: "session", closed: &closed}} }),
+	)
+	err = d.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := d.NewScope()
+	var sess *Session
+	err = s.Inject(&sess)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Close(context.Background())
+	if err != nil || !reflect.DeepEqual(closed, []string{"session", "tx"}) {
+		t.Fatal(err, closed)
+	}
+	err = s.Close(context.Background())
+	if err != nil || len(closed) != 2 {
+		t.Fatal(err, closed)
+	}
+}
+
+func TestChild(t *testing.T) {
+	type DB struct{ Name string }
+	type Tenant struct {
+		DB   *DB
+		Name string
+	}
+
+	var dbs int
+	root := New()
+	err := root.Provide(func() *DB {
+		dbs++
+		return &DB{Name: "root"}
+	}, "root")
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = root.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var tenants []*Tenant
+	for _, name := range []string{"t1", "t2"} {
+		c := root.Child()
+		err = c.Provide(name, func(db *DB, name string) *Tenant {
+			return &Tenant{DB: db, Name: name}
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = c.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var tenant *Tenant
+		err = c.Inject(&tenant)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tenants = append(tenants, tenant)
+	}
+	if dbs != 1 || tenants[0].DB != tenants[1].DB || tenants[0].Name != "t1" || tenants[1].Name != "t2" {
+		t.Fatal(dbs, tenants)
+	}
+	var name string
+	if root.Inject(&name); name != "root" {
+		t.Fatal(name)
+	}
+	var tenant *Tenant
+	if err = root.Inject(&tenant); err == nil {
+		t.Fatal()
+	}
+
+	root = New().UseRunner(AsyncRunner())
+	for i := 0; i < 10; i++ {
+		var (
+			wg     sync.WaitGroup
+			errs   [2]error
+			failed = errors.New("child1 failed")
+		)
+		for c := range errs {
+			c := c
+			child := root.Child()
+			child.Provide(func() (int, error) {
+				time.Sleep(time.Millisecond)
+				if c == 1 {
+					return 0, failed
+				}
+				return c, nil
+			})
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				errs[c] = child.Run()
+			}()
+		}
+		wg.Wait()
+		if errs[0] != nil || !errors.Is(errs[1], failed) {
+			t.Fatal(errs)
+		}
+	}
+}
+
+type store interface {
+	Get(key string) string
+}
+
+type memStore map[string]string
+
+func (m memStore) Get(key string) string { return m[key] }
+
+type sqlStore struct{}
+
+func (sqlStore) Get(key string) string { return "" }
+
+func TestInterfaceBinding(t *testing.T) {
+	d := New()
+	err := d.Provide(
+		func() memStore { return memStore{"a": "1"} },
+		func(s store) string { return s.Get("a") },
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Run(); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Fatal(err)
+	}
+
+	d = New().UseInterfaceBinding(true)
+	d.Provide(
+		func() memStore { return memStore{"a": "1"} },
+		func(s store) string { return s.Get("a") },
+	)
+	if err = d.Run(); err != nil {
+		t.Fatal(err)
+	}
+	var s string
+	if err = d.Inject(&s); err != nil || s != "1" {
+		t.Fatal(err, s)
+	}
+
+	d = New().UseInterfaceBinding(true)
+	d.Provide(
+		memStore{},
+		sqlStore{},
+		func(s store) string { return s.Get("a") },
+	)
+	err = d.Run()
+	if err == nil || !strings.Contains(err.Error(), "ambiguous") || !strings.Contains(err.Error(), "di.sqlStore") {
+		t.Fatal(err)
+	}
+
+	d = New().UseInterfaceBinding(true)
+	d.Provide(
+		memStore{"a": "1"},
+		OptNamed("sql", sqlStore{}),
+		func(args struct {
+			Store store `dep:"sql"`
+		}) string {
+			return args.Store.Get("a") + "sql"
+		},
+	)
+	if err = d.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Inject(&s); err != nil || s != "sql" {
+		t.Fatal(err, s)
+	}
+}
+
+func TestOptional(t *testing.T) {
+	type Cache struct{}
+	type Result struct {
+		Cache  Optional[*Cache]
+		Name   string
+		Absent bool
+	}
+
+	d := New()
+	err := d.Provide(
+		OptNamed("Name", "name"),
+		func(cache Optional[*Cache], args struct {
+			Name  string
+			Cache *Cache `dep:",optional"`
+			Age   int    `dep:"Age,optional"`
+		}) *Result {
+			return &Result{Cache: cache, Name: args.Name, Absent: args.Cache == nil && args.Age == 0}
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		res   *Result
+		cache Optional[*Cache]
+	)
+	err = d.Inject(&res, &cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Cache.Valid || res.Cache.Value != nil || res.Name != "name" || !res.Absent || cache.Valid {
+		t.Fatal(res, cache)
+	}
+
+	d = New()
+	d.Provide(
+		func() *Cache { return &Cache{} },
+		func(cache Optional[*Cache]) *Result {
+			return &Result{Cache: cache}
+		},
+	)
+	err = d.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.Inject(&res, &cache)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Cache.Valid || res.Cache.Value == nil || cache.Value != res.Cache.Value {
+		t.Fatal(res, cache)
+	}
+
+	d = New()
+	err = d.Provide(func(*Optional[int]) string { return "" })
+	if err == nil || !strings.Contains(err.Error(), "instead of pointer") {
+		t.Fatal(err)
+	}
+	var pcache *Optional[*Cache]
+	if err = New().Inject(&pcache); err == nil || !strings.Contains(err.Error(), "instead of pointer") {
+		t.Fatal(err)
+	}
+}
+
+type handler interface {
+	Route() string
+}
+
+type route string
+
+func (r route) Route() string { return string(r) }
+
+func TestGroup(t *testing.T) {
+	d := New()
+	d.UseRunner(AsyncRunner())
+	err := d.Provide(
+		OptGroup("routes", route("/static")),
+		OptGroup("routes", func() handler { return route("/users") }),
+		OptGroup("routes", func() handler { return route("/topics") }),
+		func() (res struct {
+			Handler handler `dep:"group:routes"`
+		}) {
+			res.Handler = route("/posts")
+			return
+		},
+		func(args struct {
+			Handlers []handler `dep:"group:routes"`
+			Checkers []handler `dep:"group:checkers,optional"`
+		}) []string {
+			var routes []string
+			for _, h := range args.Handlers {
+				routes = append(routes, h.Route())
+			}
+			return routes
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		routes   []string
+		handlers []handler
+	)
+	err = d.Inject(&routes, OptGroup("routes", &handlers))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(routes, []string{"/static", "/users", "/topics", "/posts"}) || len(handlers) != 4 {
+		t.Fatal(routes, handlers)
+	}
+
+	d = New()
+	d.Provide(func(args struct {
+		Handlers []handler `dep:"group:routes"`
+	}) {
+	})
+	err = d.Run()
+	if err == nil || !strings.Contains(err.Error(), "group:routes not found") {
+		t.Fatal(err)
+	}
+	d = New()
+	d.Provide(
+		OptGroup("routes", route("/static")),
+		OptGroup("routes", func() int { return 1 }),
+		func(args struct {
+			Handlers []handler `dep:"group:routes"`
+		}) {
+		},
+	)
+	err = d.Validate()
+	if err == nil || !strings.Contains(err.Error(), "contributor int of group routes is not assignable") {
+		t.Fatal(err)
+	}
+}
+
+func TestNamedMap(t *testing.T) {
+	type Client struct{ Region string }
+
+	d := New()
+	d.UseRunner(AsyncRunner())
+	err := d.Provide(
+		&Client{Region: "default"},
+		OptNamed("us", &Client{Region: "us"}),
+		func() (res struct {
+			Client *Client `dep:"eu"`
+		}) {
+			res.Client = &Client{Region: "eu"}
+			return
+		},
+		func(args struct {
+			Clients map[string]*Client `dep:",map"`
+			Ints    map[string]int     `dep:",map,optional"`
+		}) []string {
+			var regions []string
+			for name, c := range args.Clients {
+				if name != c.Region {
+					return nil
+				}
+				regions = append(regions, name)
+			}
+			sort.Strings(regions)
+			return regions
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var regions []string
+	err = d.Inject(&regions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(regions, []string{"eu", "us"}) {
+		t.Fatal(regions)
+	}
+
+	d = New()
+	d.Provide(func(args struct {
+		Clients map[string]*Client `dep:",map"`
+	}) {
+	})
+	err = d.Run()
+	if err == nil || !strings.Contains(err.Error(), "#map not found") {
+		t.Fatal(err)
+	}
+}
+
+type lazyA struct {
+	B Lazy[*lazyB]
+}
+
+type lazyB struct {
+	A *lazyA
+}
+
+func TestLazy(t *testing.T) {
+	type Expensive struct{}
+	type Service struct {
+		Expensive Lazy[*Expensive]
+	}
+
+	var constructed int
+	d := New()
+	err := d.Provide(
+		func(b Lazy[*lazyB]) *lazyA { return &lazyA{B: b} },
+		func(a *lazyA) *lazyB { return &lazyB{A: a} },
+		func() *Expensive {
+			constructed++
+			return &Expensive{}
+		},
+		func(e Lazy[*Expensive]) *Service { return &Service{Expensive: e} },
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var (
+		a *lazyA
+		s *Service
+	)
+	err = d.Inject(&a, &s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := a.B.Get()
+	if err != nil || b.A != a {
+		t.Fatal(err)
+	}
+	if constructed != 0 {
+		t.Fatal(constructed)
+	}
+	e1, err := s.Expensive.Get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var e2 Lazy[*Expensive]
+	err = d.Inject(&e2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e, err := e2.Get(); err != nil || e != e1 || constructed != 1 {
+		t.Fatal(err, constructed)
+	}
+
+	var zero Lazy[int]
+	if _, err = zero.Get(); err == nil {
+		t.Fatal()
+	}
+
+	var l *Lazy[int]
+	if err = d.Inject(&l); err == nil || !strings.Contains(err.Error(), "instead of pointer") {
+		t.Fatal(err)
+	}
+	err = New().Provide(func(*Lazy[int]) string { return "" })
+	if err == nil || !strings.Contains(err.Error(), "instead of pointer") {
+		t.Fatal(err)
+	}
+}
+
+func newMemStore() memStore {
+	return memStore{"a": "1"}
+}
+
+func TestGeneric(t *testing.T) {
+	d := New()
+	err := ProvideFunc[store](d, newMemStore)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = Provide[handler](d, route("/"), WithName("root"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.Provide(OptNamed("I1", 1), OptNamed("I2", 2), func(s store) string { return s.Get("a") })
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = d.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := Get[store](d)
+	if err != nil || s.Get("a") != "1" {
+		t.Fatal(err)
+	}
+	if MustGet[string](d) != "1" || MustGet[handler](d, WithName("root")).Route() != "/" {
+		t.Fatal()
+	}
+	if i, err := Named[int](d, "I2"); err != nil || i != 2 {
+		t.Fatal(err, i)
+	}
+	if _, err = Get[float64](d); err == nil {
+		t.Fatal()
+	}
+	if err = ProvideFunc[store](d, func() int { return 0 }); err == nil {
+		t.Fatal()
+	}
+
+	d = New()
+	err = ProvideFunc[store](d, func() memStore { return memStore{"a": "primary"} }, WithName("primary"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ProvideFunc[store](d, func() (memStore, error) { return memStore{"a": "secondary"}, nil }, WithName("secondary"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Run(); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"primary", "secondary"} {
+		if s, err := Named[store](d, name); err != nil || s.Get("a") != name {
+			t.Fatal(name, err)
+		}
+	}
+}
+
+func TestValidate(t *testing.T) {
+	var called bool
+	d := New()
+	d.Provide(
+		func() int { called = true; return 0 },
+		func(int) uint { return 0 },
+		func(args struct {
+			Float float64
+			Group []handler `dep:"group:routes"`
+		}) {
+		},
+		func(uint8) int8 { return 0 },
+		func(int8) uint8 { return 0 },
+		func(int16) int16 { return 0 },
+	)
+	err := d.Validate()
+	if err == nil || called {
+		t.Fatal(err)
+	}
+	msg := err.Error()
+	for _, s := range []string{
+		"dependency float64#Float not found",
+		"group:routes not found",
+		"cycle dependencies: [go-di.TestValidate.func4 go-di.TestValidate.func5 go-di.TestValidate.func4]",
+		"cycle dependencies: [go-di.TestValidate.func6 go-di.TestValidate.func6]",
+	} {
+		if !strings.Contains(msg, s) {
+			t.Fatal(msg)
+		}
+	}
+
+	d = New()
+	d.Provide(func() int { called = true; return 0 }, func(int) uint { return 0 })
+	if err = d.Validate(); err != nil || called {
+		t.Fatal(err)
+	}
+}
+
+func TestGraph(t *testing.T) {
+	d := New()
+	d.Provide(
+		OptNamed("Name", "name"),
+		func(name string) int { return len(name) },
+		func(int, float32) uint { return 0 },
+		func(int8) uint8 { return 0 },
+		func(uint8) int8 { return 0 },
+	)
+	g := d.Graph()
+	if len(g.Nodes) != 6 || len(g.Edges) != 5 {
+		t.Fatal(g)
+	}
+	var unsatisfied, cyclic int
+	for _, e := range g.Edges {
+		if e.Unsatisfied {
+			unsatisfied++
+		}
+		if e.Cyclic {
+			cyclic++
+		}
+	}
+	if unsatisfied != 1 || cyclic != 2 {
+		t.Fatal(g.Edges)
+	}
+
+	var buf bytes.Buffer
+	if err := g.WriteDOT(&buf); err != nil || !strings.Contains(buf.String(), `p0 -> p1 [label="string"];`) {
+		t.Fatal(err, buf.String())
+	}
+	buf.Reset()
+	if err := g.WriteMermaid(&buf); err != nil || !strings.Contains(buf.String(), `m0 -.->|"float32"| p2`) {
+		t.Fatal(err, buf.String())
+	}
+	buf.Reset()
+	var decoded Graph
+	if err := g.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil || !reflect.DeepEqual(&decoded, g) {
+		t.Fatal(err, buf.String())
+	}
+}
+
+func TestErrors(t *testing.T) {
+	d := New()
+	err := d.Provide(int(1), int(2))
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || conflict.Dependency != "int" {
+		t.Fatal(err)
+	}
+
+	errProvider := errors.New("ERROR")
+	d = New().UseRunner(AsyncRunner())
+	d.Provide(
+		func() (int, error) { return 0, errProvider },
+		func() (uint, error) { return 0, errProvider },
+	)
+	err = d.Run()
+	var (
+		perrs ProviderErrors
+		perr  *ProviderError
+	)
+	if !errors.Is(err, errProvider) || !errors.As(err, &perrs) || len(perrs) != 2 || !errors.As(err, &perr) {
+		t.Fatal(err)
+	}
+
+	d = New()
+	d.Provide(func(float64) {}, func(int8) uint8 { return 0 }, func(uint8) int8 { return 0 })
+	err = d.Validate()
+	var (
+		missing *MissingDependencyError
+		cycle   *CycleError
+	)
+	if !errors.As(err, &missing) || missing.Dependency != "float64" || !errors.As(err, &cycle) || len(cycle.Path) != 3 {
+		t.Fatal(err)
+	}
+	d = New()
+	d.Provide(
+		OptNamed("int8", OptTransient(func(uint8) int8 { return 0 })),
+		OptNamed("uint8", OptTransient(func(int16) uint8 { return 0 })),
+		OptNamed("int16", OptTransient(func(int8) int16 { return 0 })),
+	)
+	_, err = Get[int8](d)
+	if !errors.As(err, &cycle) || strings.Join(cycle.Path, ",") != "int8,uint8,int16,int8" {
+		t.Fatal(err)
+	}
+}
+
+func TestContext(t *testing.T) {
+	type key struct{}
+	ctx := context.WithValue(context.Background(), key{}, "value")
+	d := New()
+	d.Provide(func(ctx context.Context, lc Lifecycle) string {
+		lc.Append(Hook{OnStart: func(ctx context.Context) error {
+			if ctx.Value(key{}) != "value" {
+				return errors.New("context not propagated to hooks")
+			}
+			return nil
+		}})
+		return ctx.Value(key{}).(string)
+	})
+	err := d.RunContext(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := MustGet[string](d); s != "value" {
+		t.Fatal(s)
+	}
+
+	d = New()
+	d.Provide(OptTimeout(time.Millisecond*10, func(ctx context.Context) (int, error) {
+		<-ctx.Done()
+		return 0, ctx.Err()
+	}))
+	err = d.Run()
+	var timeout *TimeoutError
+	if !errors.As(err, &timeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal(err)
+	}
+
+	d = New()
+	d.Provide(OptTimeout(time.Millisecond*10, func() string {
+		time.Sleep(time.Millisecond * 30)
+		return "late"
+	}))
+	if err = d.Run(); !errors.As(err, &timeout) {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 50)
+	var uninitialized *UninitializedError
+	if s, err := Get[string](d); !errors.As(err, &uninitialized) {
+		t.Fatal(s, err)
+	}
+
+	var (
+		runs    int32
+		started int32
+		stopped int32
+	)
+	d = New()
+	d.Provide(OptTimeout(time.Millisecond*10, func(lc Lifecycle) string {
+		if atomic.AddInt32(&runs, 1) == 1 {
+			time.Sleep(time.Millisecond * 30)
+		}
+		lc.Append(Hook{
+			OnStart: func(context.Context) error {
+				atomic.AddInt32(&started, 1)
+				return nil
+			},
+			OnStop: func(context.Context) error {
+				atomic.AddInt32(&stopped, 1)
+				return nil
+			},
+		})
+		return "value"
+	}))
+	if err = d.Run(); !errors.As(err, &timeout) {
+		t.Fatal(err)
+	}
+	if err = d.Run(); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(time.Millisecond * 50)
+	if err = d.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if started != 1 || stopped != 1 {
+		t.Fatal(started, stopped)
+	}
+
+	for _, runner := range []Runner{SyncRunner(), AsyncRunner(), PoolRunner(2)} {
+		var ran bool
+		ctx, cancel := context.WithCancel(context.Background())
+		d = New().UseRunner(runner)
+		d.Provide(
+			func() int {
+				cancel()
+				return 1
+			},
+			func(int) uint {
+				ran = true
+				return 1
+			},
+		)
+		err = d.RunContext(ctx)
+		if err != context.Canceled || ran {
+			t.Fatal(err, ran)
+		}
+	}
+}
+
+type countRunner struct {
+	Runner
+	names []string
+}
+
+func (c *countRunner) Schedule(ctx context.Context, t Task) error {
+	c.names = append(c.names, t.Name())
+	return c.Runner.Schedule(ctx, t)
+}
+
+func TestPoolRunner(t *testing.T) {
+	var (
+		mu              sync.Mutex
+		running, maxRun int
+	)
+	work := func() {
+		mu.Lock()
+		running++
+		if running > maxRun {
+			maxRun = running
+		}
+		mu.Unlock()
+		time.Sleep(time.Millisecond * 5)
+		mu.Lock()
+		running--
+		mu.Unlock()
+	}
+
+	runner := &countRunner{Runner: PoolRunner(2)}
+	d := New().UseRunner(runner)
+	d.Provide(
+		func() int8 { work(); return 1 },
+		func() int16 { work(); return 2 },
+		func() int32 { work(); return 3 },
+		func() int64 { work(); return 4 },
+		func(a int8, b int16, c int32, d int64) int {
+			return int(a) + int(b) + int(c) + int(d)
+		},
+	)
+	err := d.Run()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := MustGet[int](d); n != 10 {
+		t.Fatal(n)
+	}
+	if maxRun > 2 || len(runner.names) != 5 {
+		t.Fatal(maxRun, runner.names)
+	}
+
+	var skipped int32 = 1
+	d = New().UseRunner(PoolRunner(2))
+	d.Provide(
+		func() (int, error) { return 0, errors.New("failed") },
+		func(int) uint { atomic.StoreInt32(&skipped, 0); return 0 },
+	)
+	var perr *ProviderError
+	if err = d.Run(); !errors.As(err, &perr) || perr.Err.Error() != "failed" {
+		t.Fatal(err)
+	}
+	if atomic.LoadInt32(&skipped) != 1 {
+		t.Fatal("should be skipped")
+	}
+}
+
+func TestDecorate(t *testing.T) {
+	for _, runner := range []Runner{SyncRunner(), AsyncRunner()} {
+		d := New().UseRunner(runner)
+		err := d.Provide(
+			func() string { return "db" },
+			func(s string) []byte { return []byte(s) },
+			OptNamed("Name", "primary"),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = d.Decorate(
+			func(s string) string { return "trace(" + s + ")" },
+			func(s string, n int) string { return fmt.Sprintf("metrics%d(%s)", n, s) },
+			func(args struct{ Name string }) (res struct{ Name string }) {
+				res.Name = args.Name + "-decorated"
+				return res
+			},
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		d.Provide(func() int { return 1 })
+		err = d.Run()
+		if err != nil {
+			t.Fatal(err)
+		}
+		var (
+			b    []byte
+			name = MustGet[string](d, WithName("Name"))
+		)
+		d.Inject(&b)
+		if string(b) != "metrics1(trace(db))" || name != "primary-decorated" {
+			t.Fatal(string(b), name)
+		}
+
+		d = New().UseRunner(runner)
+		d.Provide(
+			func() int { time.Sleep(time.Millisecond * 10); return 1 },
+			func(int) string { return "orig" },
+		)
+		d.Decorate(func() string { return "decorated" })
+		if err = d.Validate(); err != nil {
+			t.Fatal(err)
+		}
+		if err = d.Run(); err != nil {
+			t.Fatal(err)
+		}
+		if s := MustGet[string](d); s != "decorated" {
+			t.Fatal(s)
+		}
+	}
+
+	d := New()
+	err := d.Decorate(func(s string) string { return s })
+	var missing *MissingDependencyError
+	if !errors.As(err, &missing) {
+		t.Fatal(err)
+	}
+}
+
+func TestOverride(t *testing.T) {
+	var realRuns int
+	d := New()
+	d.Provide(
+		func() string {
+			realRuns++
+			return "real"
+		},
+		func(s string) []byte { return []byte(s) },
+	)
+	d.Decorate(func(s string) string { return s + "-decorated" })
+	var conflict *ConflictError
+	if err := d.Provide("fake"); !errors.As(err, &conflict) {
+		t.Fatal(err)
+	}
+
+	c, err := d.Clone("fake")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, inj := range []*Injector{d, c} {
+		if err = inj.Run(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if b := MustGet[[]byte](d); string(b) != "real-decorated" || realRuns != 1 {
+		t.Fatal(string(b), realRuns)
+	}
+	if b := MustGet[[]byte](c); string(b) != "fake-decorated" {
+		t.Fatal(string(b))
+	}
+
+	d = New()
+	d.Provide(func() (string, int) { return "real", 1 })
+	err = d.Replace(func() string { return "fake" })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if s, n := MustGet[string](d), MustGet[int](d); s != "fake" || n != 1 {
+		t.Fatal(s, n)
+	}
+}
+
+func TestInvoke(t *testing.T) {
+	d := New()
+	d.Provide("db", OptNamed("Port", 8080))
+	if err := d.Run(); err != nil {
+		t.Fatal(err)
+	}
+	out, err := d.Invoke(func(s string, args struct{ Port int }) (string, error) {
+		return fmt.Sprintf("%s:%d", s, args.Port), nil
+	})
+	if err != nil || len(out) != 1 || out[0].String() != "db:8080" {
+		t.Fatal(out, err)
+	}
+	_, err = d.Invoke(func(s string) error { return errors.New(s) })
+	if err == nil || err.Error() != "db" {
+		t.Fatal(err)
+	}
+	var missing *MissingDependencyError
+	if _, err = d.NewScope().Invoke(func(float64) {}); !errors.As(err, &missing) {
+		t.Fatal(err)
+	}
+}
+
+func TestRunFor(t *testing.T) {
+	var runs []string
+	d := New()
+	d.Provide(
+		func() int { runs = append(runs, "int"); return 1 },
+		func(n int) string { runs = append(runs, "string"); return fmt.Sprint(n) },
+		func() (float64, error) { runs = append(runs, "float64"); return 0, errors.New("unused") },
+		func(float64) uint { runs = append(runs, "uint"); return 1 },
+	)
+	var s string
+	err := d.RunFor(&s, func(n int) error {
+		if n != 1 {
+			return errors.New("unexpected")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "1" || strings.Join(runs, ",") != "int,string" {
+		t.Fatal(s, runs)
+	}
+
+	var u uint
+	if err = d.Inject(&u); err == nil || strings.Join(runs, ",") != "int,string,float64" {
+		t.Fatal(err, runs)
+	}
+	var missing *MissingDependencyError
+	if err = d.RunFor(new(int8)); !errors.As(err, &missing) {
+		t.Fatal(err)
+	}
+}
+
+type moduleDB struct {
+	dsn string
+}
+
+func TestModule(t *testing.T) {
+	db := Module("db",
+		OptNamed("DSN", "mysql://db"),
+		func(args struct{ DSN string }) *moduleDB {
+			return &moduleDB{dsn: args.DSN}
+		},
+	).Export((*moduleDB)(nil))
+	auth := Module("auth",
+		db,
+		OptNamed("DSN", "mysql://auth"),
+		func(db *moduleDB, args struct{ DSN string }) []string {
+			return []string{db.dsn, args.DSN}
+		},
+	).Export(reflect.TypeOf([]string(nil)), (*moduleDB)(nil))
+
+	var names []string
+	d := New().UseLogger(nameLogger{names: &names})
+	err := d.Provide(auth, OptNamed("DSN", "mysql://app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Run(); err != nil {
+		t.Fatal(err)
+	}
+	dsns := MustGet[[]string](d)
+	if strings.Join(dsns, ",") != "mysql://db,mysql://auth" || MustGet[string](d, WithName("DSN")) != "mysql://app" {
+		t.Fatal(dsns)
+	}
+	if !strings.HasPrefix(names[0], "auth/db/") || !strings.HasPrefix(names[1], "auth/") {
+		t.Fatal(names)
+	}
+
+	d = New()
+	err = d.Provide(Module("private", "value").Export(1), func(s string) int { return 1 })
+	if err != nil {
+		t.Fatal(err)
+	}
+	var missing *MissingDependencyError
+	if err = d.Run(); !errors.As(err, &missing) {
+		t.Fatal(err)
+	}
+
+	d = New()
+	err = d.Provide(Module("m", func() int { return 1 }), func() int { return 2 })
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || !strings.Contains(conflict.Providers[0], "m/") {
+		t.Fatal(err)
+	}
+
+	d = New()
+	err = d.Provide(
+		Module("store",
+			func() store { return memStore{} },
+			func() int { return 1 },
+		).Export((*store)(nil)),
+		func(store) string { return "" },
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = Get[int](d); !errors.As(err, &missing) {
+		t.Fatal(err)
+	}
+	err = New().Provide(Module("nil", 1).Export((store)(nil)))
+	if err == nil || !strings.Contains(err.Error(), "module nil: exported type must not be nil") {
+		t.Fatal(err)
+	}
+}
+
+type nameLogger struct {
+	names *[]string
+}
+
+func (l nameLogger) Begin(name string, at time.Time) {
+	*l.names = append(*l.names, name)
+}
+
+func (l nameLogger) End(name string, at time.Time, dur time.Duration) {}
+
+func TestProfile(t *testing.T) {
+	t.Setenv("TEST_DI_PROFILES", "test, dev")
+
+	newStores := func(d *Injector) error {
+		return d.Provide(
+			OptProfile("prod", OptTyped(sqlStore{}, typeOf[store]())),
+			OptProfile("dev", OptProfile("test", OptTyped(memStore{"name": "mem"}, typeOf[store]()))),
+			OptWhen(func() bool { return false }, 1),
+			Module("m", 2).Export(0),
+			OptWhen(func() bool { return false }, Module("disabled", "")),
+		)
+	}
+	for profile, expect := range map[string]string{"prod": "", "": "mem"} {
+		d := NewAndParseEnv("TEST_")
+		if profile != "" {
+			d.UseProfiles(profile)
+		}
+		if err := newStores(d); err != nil {
+			t.Fatal(err)
+		}
+		if err := d.Run(); err != nil {
+			t.Fatal(err)
+		}
+		if s := MustGet[store](d).Get("name"); s != expect || MustGet[int](d) != 2 {
+			t.Fatal(profile, s)
+		}
+		if _, err := Get[string](d); err == nil {
+			t.Fatal("disabled module should not be provided")
+		}
+	}
+}
+
+type testConfig struct {
+	Addr    string        `json:"addr" env:"ADDR" flag:"addr"`
+	Timeout time.Duration `env:"TIMEOUT"`
+	Debug   bool          `json:"debug" flag:"debug"`
+	Tags    []string      `env:"TAGS"`
+	DB      struct {
+		DSN  string `json:"dsn" env:"DB_DSN"`
+		Pool int    `json:"pool"`
+	} `dep:"-"`
+}
+
+func TestProvideConfig(t *testing.T) {
+	file := t.TempDir() + "/config.json"
+	err := os.WriteFile(file, []byte(`{"addr": ":80", "db": {"dsn": "file", "pool": 4}}`), 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_ADDR", ":8080")
+	t.Setenv("TEST_TIMEOUT", "3s")
+	t.Setenv("TEST_TAGS", "a, b")
+
+	cfg := testConfig{Addr: ":0"}
+	cfg.DB.DSN = "default"
+	d := New()
+	err = d.ProvideConfig(OptDecompose(&cfg),
+		ConfigFile(file),
+		ConfigEnvPrefix("TEST_"),
+		ConfigFlags(nil, []string{"-debug", "-addr", ":9090"}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = d.Run(); err != nil {
+		t.Fatal(err)
+	}
+	c := MustGet[testConfig](d)
+	if c.Addr != ":9090" || c.Timeout != time.Second*3 || !c.Debug || strings.Join(c.Tags, ",") != "a,b" ||
+		c.DB.DSN != "file" || c.DB.Pool != 4 {
+		t.Fatalf("%+v", c)
+	}
+	if addr := MustGet[string](d, WithName("Addr")); addr != ":9090" {
+		t.Fatal(addr)
+	}
+
+	t.Setenv("TEST_TIMEOUT", "3")
+	err = New().ProvideConfig(&testConfig{}, ConfigEnvPrefix("TEST_"))
+	if err == nil || !strings.Contains(err.Error(), "TEST_TIMEOUT") {
+		t.Fatal(err)
+	}
+	err = New().ProvideConfig(&testConfig{}, ConfigFile("config.yaml"))
+	if err == nil {
+		t.Fatal("decoder of yaml should not be found")
+	}
+
+	d = New()
+	err = d.ProvideConfig(OptProfile("prod", &testConfig{}), ConfigFile("config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var missing *MissingDependencyError
+	if _, err = Get[testConfig](d); !errors.As(err, &missing) {
+		t.Fatal(err)
+	}
+	d = New()
+	d.ProvideConfig(OptNamed("primary", OptGroup("configs", &testConfig{Addr: ":1"})))
+	d.ProvideConfig(OptNamed("secondary", OptGroup("configs", &testConfig{Addr: ":2"})))
+	var configs []testConfig
+	if err = d.Inject(OptGroup("configs", &configs)); err != nil || len(configs) != 2 {
+		t.Fatal(err, configs)
+	}
+}
+
+func TestReport(t *testing.T) {
+	d := New().UseRunner(AsyncRunner())
+	d.Provide(
+		OptNamed("fast", func() int8 { time.Sleep(time.Millisecond * 5); return 1 }),
+		OptNamed("slow", func() int16 { time.Sleep(time.Millisecond * 30); return 2 }),
+		OptNamed("server", func(int8, int16) int { time.Sleep(time.Millisecond * 5); return 3 }),
+		1.0,
+	)
+	if err := d.Run(); err != nil {
+		t.Fatal(err)
+	}
+	r := d.Report()
+	if len(r.Providers) != 3 || strings.Join(r.CriticalPath, ",") != "slow,server" || r.Total < time.Millisecond*35 {
+		t.Fatal(r)
+	}
+	server := r.Providers[2]
+	if server.Name != "server" || !server.Critical || server.Wait < time.Millisecond*25 ||
+		server.Start < time.Millisecond*30 {
+		t.Fatal(server)
+	}
+	var buf bytes.Buffer
+	if err := r.WriteTable(&buf); err != nil || !strings.Contains(buf.String(), "critical path: slow -> server") {
+		t.Fatal(buf.String(), err)
+	}
+}
+
+func TestTraceLogger(t *testing.T) {
+	trace := NewTraceLogger()
+	d := New().UseRunner(AsyncRunner()).UseLogger(trace)
+	d.Provide(
+		OptNamed("a", func() int8 { time.Sleep(time.Millisecond * 5); return 1 }),
+		OptNamed("b", func() int16 { time.Sleep(time.Millisecond * 5); return 2 }),
+	)
+	if err := d.Run(); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := trace.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var result struct {
+		TraceEvents []struct {
+			Name  string  `json:"name"`
+			Phase string  `json:"ph"`
+			Time  float64 `json:"ts"`
+			Tid   uint64  `json:"tid"`
+		} `json:"traceEvents"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &result); err != nil {
+		t.Fatal(err)
+	}
+	var (
+		spans   = make(map[string]int)
+		threads = make(map[uint64]bool)
+	)
+	for _, e := range result.TraceEvents {
+		switch e.Phase {
+		case "B", "E":
+			spans[e.Name]++
+			threads[e.Tid] = true
+		}
+	}
+	if spans["a"] != 2 || spans["b"] != 2 || len(threads) != 2 {
+		t.Fatal(buf.String())
+	}
+}
+
+func panicProvider() int {
+	panic("boom")
+}
+
+func TestPanic(t *testing.T) {
+	for _, runner := range []Runner{SyncRunner(), AsyncRunner(), PoolRunner(2)} {
+		d := New().UseRunner(runner)
+		d.Provide(panicProvider, func(int) uint { return 1 })
+		err := d.Run()
+		var perr *PanicError
+		if !errors.As(err, &perr) || perr.Value != "boom" || !strings.HasSuffix(perr.Provider, "panicProvider") ||
+			!bytes.Contains(perr.Stack, []byte("panicProvider")) {
+			t.Fatal(err)
+		}
+	}
+
+	d := New()
+	d.Provide(OptTransient(func() string { panic("transient") }))
+	d.Provide(func(lc Lifecycle) uint {
+		lc.Append(Hook{OnStart: func(context.Context) error { panic("hook") }})
+		return 1
+	})
+	var perr *PanicError
+	if err := d.Run(); !errors.As(err, &perr) || perr.Value != "hook" {
+		t.Fatal(err)
+	}
+	if _, err := Get[string](d); !errors.As(err, &perr) || perr.Value != "transient" {
+		t.Fatal(err)
+	}
+
+	for _, runner := range []Runner{SyncRunner(), AsyncRunner(), PoolRunner(2)} {
+		d := New().UseRunner(runner).UseLogger(panicLogger{})
+		d.Provide(func() int { return 1 })
+		err := d.Run()
+		if !errors.As(err, &perr) || perr.Value != "begin" {
+			t.Fatal(err)
+		}
+	}
+}
+
+type panicLogger struct{}
+
+func (panicLogger) Begin(name string, at time.Time) {
+	panic("begin")
+}
+
+func (panicLogger) End(name string, at time.Time, dur time.Duration) {}
+
+type lazyC struct{}
+
+func TestLazyInProvider(t *testing.T) {
+	for _, runner := range []Runner{SyncRunner(), AsyncRunner(), PoolRunner(2)} {
+		var cRuns int32
+		d := New().UseRunner(runner)
+		d.Provide(
+			func(b Lazy[*lazyB]) (*lazyA, error) {
+				_, err := b.Get()
+				return &lazyA{B: b}, err
+			},
+			func(*lazyC) *lazyB { return &lazyB{} },
+			func() *lazyC {
+				atomic.AddInt32(&cRuns, 1)
+				time.Sleep(time.Millisecond * 10)
+				return &lazyC{}
+			},
+		)
+		if err := d.Run(); err != nil {
+			t.Fatal(err)
+		}
+		if cRuns != 1 {
+			t.Fatal(cRuns)
+		}
+
+		d = New().UseRunner(runner)
+		d.Provide(
+			func(b Lazy[*lazyB]) (*lazyA, error) {
+				_, err := b.Get()
+				return &lazyA{B: b}, err
+			},
+			func(a *lazyA) *lazyB { return &lazyB{A: a} },
+		)
+		var cycle *CycleError
+		if err := d.Run(); !errors.As(err, &cycle) {
+			t.Fatal(err)
+		}
+
+		d = New().UseRunner(runner)
+		d.Provide(
+			func(b Lazy[*lazyB]) (*lazyA, error) {
+				time.Sleep(time.Millisecond * 10)
+				_, err := b.Get()
+				return &lazyA{B: b}, err
+			},
+			func(a Lazy[*lazyA]) (*lazyB, error) {
+				time.Sleep(time.Millisecond * 10)
+				_, err := a.Get()
+				return &lazyB{}, err
+			},
+			func(*lazyA) int { return 1 },
+			func(*lazyB) uint { return 2 },
+		)
+		done := make(chan error, 1)
+		go func() { done <- d.Run() }()
+		select {
+		case err := <-done:
+			if !errors.As(err, &cycle) {
+				t.Fatal(err)
+			}
+		case <-time.After(time.Second * 5):
+			t.Fatal("deadlock")
+		}
+	}
+}
+
+type wrappedCloser struct {
+	io.Closer
+}
+
+func TestShutdownDecorated(t *testing.T) {
+	var closed []string
+	d := New()
+	d.Provide(func() io.Closer { return closer{name: "server", closed: &closed} })
+	d.Decorate(func(c io.Closer) io.Closer {
+		return wrappedCloser{Closer: closer{name: "middleware", closed: &closed}}
+	})
+	d.Provide(func(c io.Closer) int {
+		if _, ok := c.(wrappedCloser); !ok {
+			t.Error("dependency is not decorated")
+		}
+		return 1
+	})
+	if err := d.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(closed, ",") != "server" {
+		t.Fatal(closed)
+	}
+}
@@ -0,0 +1,130 @@
+package di
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+func (j *Injector) invoke(fn interface{}, r *resolution) ([]reflect.Value, error) {
+	refv, ok := fn.(reflect.Value)
+	if !ok {
+		refv = reflect.ValueOf(fn)
+	}
+	if refv.Kind() != reflect.Func {
+		return nil, fmt.Errorf("invoked value must be function: %s", refv.Type())
+	}
+	t := refv.Type()
+	p, err := j.analyseFunc("", t, refv)
+	if err != nil {
+		return nil, err
+	}
+	if p.hasLifecycle() {
+		return nil, fmt.Errorf("lifecycle is not available for invoked function: %s", p.name)
+	}
+
+	in := make([]reflect.Value, 0, len(p.depParsers))
+	for _, dp := range p.depParsers {
+		v, err := dp.Parse(r)
+		if err != nil {
+			return nil, err
+		}
+		in = append(in, v)
+	}
+	var out []reflect.Value
+	if t.IsVariadic() {
+		out = refv.CallSlice(in)
+	} else {
+		out = refv.Call(in)
+	}
+	return p.errorResolver.Resolve(out)
+}
+
+// Invoke call the function with dependencies resolved from the injector, parameters follow the same rules
+// with function providers, results are not provided as dependencies, they are returned except the error,
+// which is returned as the error. It should be called after running the injector.
+func (j *Injector) Invoke(fn interface{}) ([]reflect.Value, error) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.invoke(fn, j.newResolution(nil))
+}
+
+// Invoke is same as Injector.Invoke, scoped and transient providers will be run if necessary.
+func (s *Scope) Invoke(fn interface{}) ([]reflect.Value, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j := s.injector
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	return j.invoke(fn, j.newResolution(s))
+}
+
+// targetDeps analyse dependencies of the destination pointer or function.
+func (j *Injector) targetDeps(v interface{}) ([]*dependency, error) {
+	o := parseOptionValue(v)
+	switch o.Value.Kind() {
+	case reflect.Func:
+		p, err := j.analyseFunc("", o.Value.Type(), o.Value)
+		if err != nil {
+			return nil, err
+		}
+		return p.deps, nil
+	case reflect.Ptr:
+	default:
+		return nil, fmt.Errorf("target must be pointer or function: %s", o.Value.Type())
+	}
+	t := o.Value.Type().Elem()
+	dep, _, err := j.analyseDependency(t, fieldTag{Name: o.Name, Group: o.Group})
+	if err != nil {
+		return nil, err
+	}
+	if dep.Optional || dep.Group != "" || dep.Map || j.match(dep) != nil ||
+		t.Kind() != reflect.Struct || (t.Name() != "" && !o.Decomposable) {
+		return []*dependency{dep}, nil
+	}
+	deps, _, err := j.analyseStructure(t, nil)
+	return deps, err
+}
+
+// RunFor is same as Run except that only providers required by targets are run, other providers are
+// deferred until they are firstly required, or run by next Run.
+// A target is a destination pointer like Inject, or a function like Invoke, dependencies are injected to
+// pointers and functions are invoked after running, the error returned by the function is returned.
+func (j *Injector) RunFor(targets ...interface{}) error {
+	return j.RunForContext(context.Background(), targets...)
+}
+
+// RunForContext is same as RunFor, the context is used like RunContext.
+func (j *Injector) RunForContext(ctx context.Context, targets ...interface{}) error {
+	target := &provider{
+		name:          "RunFor",
+		errorResolver: errorResolver{index: -1},
+	}
+	j.mu.RLock()
+	for _, v := range targets {
+		deps, err := j.targetDeps(v)
+		if err != nil {
+			j.mu.RUnlock()
+			return err
+		}
+		target.deps = append(target.deps, deps...)
+	}
+	j.mu.RUnlock()
+
+	err := j.run(ctx, target)
+	if err != nil {
+		return err
+	}
+	for _, v := range targets {
+		if parseOptionValue(v).Value.Kind() == reflect.Func {
+			_, err = j.Invoke(v)
+		} else {
+			err = j.Inject(v)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,104 @@
+package di
+
+import (
+	"errors"
+	"reflect"
+	"sync"
+)
+
+var lazyReftype = reflect.TypeOf((*lazyType)(nil)).Elem()
+
+type lazyType interface {
+	lazyElem() reflect.Type
+	withValue(v *lazyValue) interface{}
+}
+
+// Lazy defers the resolution of a dependency to the first call of Get, the dependent provider is run
+// without waiting for the dependency, so it's helpful to break initialization cycles.
+// Singleton providers only required lazily are not run in Run, they are run when firstly required.
+// Get could be called by providers in Run, providers of the dependency not finished yet are run on
+// demand, and cycle dependencies are reported by CycleError.
+// It's available for function parameters, structure fields and Inject destinations.
+type Lazy[T any] struct {
+	value *lazyValue
+}
+
+func (Lazy[T]) lazyElem() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+func (Lazy[T]) withValue(v *lazyValue) interface{} {
+	return Lazy[T]{value: v}
+}
+
+// Get resolve the dependency at the first call, and returns the same result for later calls.
+func (l Lazy[T]) Get() (T, error) {
+	var t T
+	if l.value == nil {
+		return t, errors.New("lazy dependency is not injected")
+	}
+	v, err := l.value.get()
+	if err == nil && v.IsValid() {
+		reflect.ValueOf(&t).Elem().Set(v)
+	}
+	return t, err
+}
+
+type lazyValue struct {
+	once    sync.Once
+	dep     *dependency
+	scope   *Scope
+	j       *Injector
+	running []*provider
+	exec    *executor
+
+	val reflect.Value
+	err error
+}
+
+func (l *lazyValue) get() (reflect.Value, error) {
+	l.once.Do(func() {
+		l.val, l.err = l.resolve()
+	})
+	return l.val, l.err
+}
+
+// resolve resolve the dependency on demand, singleton providers not finished yet are run, including
+// dependencies of them. Providers running when the Lazy is injected are recorded, and the resolution is
+// executed by a child executor of the injecting one, to report cycles instead of deadlock across goroutines.
+func (l *lazyValue) resolve() (reflect.Value, error) {
+	r := l.j.newResolution(l.scope)
+	r.running = l.running
+	r.exec = &executor{parent: l.exec}
+	r.onDemand = true
+	return l.dep.Parse(r)
+}
+
+type lazyParser struct {
+	dep *dependency
+	typ reflect.Type
+}
+
+func (l lazyParser) Parse(r *resolution) (reflect.Value, error) {
+	lv := &lazyValue{
+		dep:     l.dep,
+		scope:   r.scope,
+		j:       r.injector,
+		running: append([]*provider(nil), r.running...),
+		exec:    r.exec,
+	}
+	return reflect.ValueOf(reflect.Zero(l.typ).Interface().(lazyType).withValue(lv)), nil
+}
+
+func (l lazyParser) Resolve(r *resolution, v reflect.Value) error {
+	return nil
+}
+
+func (l lazyParser) Inject(v reflect.Value, r *resolution) error {
+	val, err := l.Parse(r)
+	if err != nil {
+		return err
+	}
+	v.Set(val)
+	return nil
+}
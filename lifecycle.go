@@ -0,0 +1,187 @@
+package di
+
+import (
+	"context"
+	"io"
+	"reflect"
+	"time"
+)
+
+var lifecycleReftype = reflect.TypeOf((*Lifecycle)(nil)).Elem()
+
+// Hook is a pair of callbacks bound to a provider, OnStart is called after the provider finished
+// successfully in Run, OnStop is called in Shutdown. Both of them are optional.
+type Hook struct {
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+}
+
+// Lifecycle is used to register hooks for a provider, a function provider receives it by declaring a
+// parameter of type Lifecycle, it's not a dependency and always available.
+type Lifecycle interface {
+	Append(h Hook)
+}
+
+// Stopper is implemented by values need to be stopped in Shutdown.
+type Stopper interface {
+	Stop(ctx context.Context) error
+}
+
+type providerLifecycle struct {
+	hooks *[]Hook
+}
+
+func (l providerLifecycle) Append(h Hook) {
+	*l.hooks = append(*l.hooks, h)
+}
+
+// timedHooks collects hooks of the provider run with timeout privately, they are appended to the provider
+// only if it's finished in time.
+type timedHooks struct {
+	provider *provider
+	hooks    []Hook
+}
+
+type lifecycleParser struct {
+	provider *provider
+}
+
+func (l lifecycleParser) Parse(r *resolution) (reflect.Value, error) {
+	hooks := &l.provider.hooks
+	if r.timed != nil && r.timed.provider == l.provider {
+		hooks = &r.timed.hooks
+	}
+	var lc Lifecycle = providerLifecycle{hooks: hooks}
+	return reflect.ValueOf(&lc).Elem(), nil
+}
+
+func (l lifecycleParser) Resolve(r *resolution, v reflect.Value) error {
+	return nil
+}
+
+func (l lifecycleParser) Inject(v reflect.Value, r *resolution) error {
+	lc, err := l.Parse(r)
+	if err != nil {
+		return err
+	}
+	v.Set(lc)
+	return nil
+}
+
+func (p *provider) hasLifecycle() bool {
+	for _, dp := range p.depParsers {
+		if _, ok := dp.(lifecycleParser); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (j *Injector) startProvider(ctx context.Context, p *provider) (err error) {
+	defer recoverProvider(p, &err)
+
+	for _, h := range p.hooks {
+		if h.OnStart == nil {
+			continue
+		}
+		err := h.OnStart(ctx)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stopValue stop the value if it implements Stopper or io.Closer, comparable values recorded in stopped are
+// skipped, so the same object returned by multiple providers is stopped only once.
+func stopValue(ctx context.Context, v reflect.Value, stopped map[interface{}]bool) error {
+	if !v.IsValid() {
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Func, reflect.Chan:
+		if v.IsNil() {
+			return nil
+		}
+	}
+	if !v.CanInterface() {
+		return nil
+	}
+	i := v.Interface()
+	s, isStopper := i.(Stopper)
+	c, isCloser := i.(io.Closer)
+	if !isStopper && !isCloser {
+		return nil
+	}
+	if reflect.ValueOf(i).Comparable() {
+		if stopped[i] {
+			return nil
+		}
+		stopped[i] = true
+	}
+	if isStopper {
+		return s.Stop(ctx)
+	}
+	return c.Close()
+}
+
+func (j *Injector) stopProvider(ctx context.Context, p *provider, stopped map[interface{}]bool, errs *providerErrors) {
+	if !p.fn.IsValid() && len(p.hooks) == 0 {
+		return
+	}
+	var (
+		begin = time.Now()
+		n     = len(errs.errs)
+	)
+	defer func() {
+		e := Event{Kind: EventShutdown, Provider: p.name, Duration: time.Since(begin)}
+		if len(errs.errs) > n {
+			e.Err = append(ProviderErrors(nil), errs.errs[n:]...)
+		}
+		j.event(e)
+	}()
+	for i := len(p.hooks) - 1; i >= 0; i-- {
+		h := p.hooks[i]
+		if h.OnStop == nil {
+			continue
+		}
+		err := h.OnStop(ctx)
+		if err != nil {
+			errs.Append(p.name, err)
+		}
+	}
+	if !p.fn.IsValid() {
+		return
+	}
+	for i := len(p.provides) - 1; i >= 0; i-- {
+		v := p.provides[i].Val
+		if p.provides[i].origin.IsValid() {
+			v = p.provides[i].origin
+		}
+		err := stopValue(ctx, v, stopped)
+		if err != nil {
+			errs.Append(p.name, err)
+		}
+	}
+}
+
+// Shutdown stop all providers finished in Run by the reverse order of their completion, it's also the
+// reverse topological order of the dependency graph.
+// For each provider, OnStop hooks are called in reverse order of registration, then values returned by
+// function providers which implement Stopper or io.Closer are stopped, the value before decorating is
+// stopped for decorated dependencies. Static values are owned by the caller and never stopped.
+// All errors are collected and returned together, each provider and value will be stopped at most once.
+func (j *Injector) Shutdown(ctx context.Context) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	var (
+		errs      providerErrors
+		stopped   = make(map[interface{}]bool)
+		providers = j.dones.clearOrder()
+	)
+	for i := len(providers) - 1; i >= 0; i-- {
+		j.stopProvider(ctx, providers[i], stopped, &errs)
+	}
+	return errs.ToError()
+}
@@ -0,0 +1,190 @@
+package di
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+)
+
+// ModuleSpec is a named set of providers created by Module, it's passed to Provide like other providers.
+type ModuleSpec struct {
+	name      string
+	providers []interface{}
+	exports   []reflect.Type
+	err       error
+}
+
+// Module create a module of providers, the providers could be any values accepted by Provide, including
+// other modules. Names of function providers are prefixed by the module path, such as "app/db/NewDB", and
+// errors occurred in providing are wrapped with the module path.
+// All dependencies provided by the module are visible to others unless Export is used.
+func Module(name string, v ...interface{}) *ModuleSpec {
+	return &ModuleSpec{
+		name:      name,
+		providers: v,
+	}
+}
+
+// Export declare types exported by the module, dependencies of other types are private, they are only
+// visible to providers of the module and it's sub-modules. Dependencies exported by a sub-module are
+// visible to others only if they are exported by the module too.
+// Each argument is a reflect.Type or a value of the type, such as (*DB)(nil). Interface types are exported by
+// pointers of them, such as (*Store)(nil), because nil values of interfaces have no type. Nil arguments are
+// reported when the module is provided.
+func (m *ModuleSpec) Export(types ...interface{}) *ModuleSpec {
+	for _, t := range types {
+		reft, ok := t.(reflect.Type)
+		if !ok {
+			reft = reflect.TypeOf(t)
+		}
+		if reft == nil {
+			if m.err == nil {
+				m.err = errors.New("exported type must not be nil, use pointer of interface such as (*Store)(nil)")
+			}
+			continue
+		}
+		if !ok && reft.Kind() == reflect.Ptr && reft.Elem().Kind() == reflect.Interface {
+			reft = reft.Elem()
+		}
+		m.exports = append(m.exports, reft)
+	}
+	return m
+}
+
+// module is an instance of ModuleSpec provided to the injector.
+type module struct {
+	spec   *ModuleSpec
+	path   string
+	parent *module
+}
+
+func (m *module) exported(t reflect.Type) bool {
+	if len(m.spec.exports) == 0 {
+		return true
+	}
+	for _, e := range m.spec.exports {
+		if e == t {
+			return true
+		}
+	}
+	return false
+}
+
+// within checks whether the module is m or a sub-module of m.
+func (m *module) within(ancestor *module) bool {
+	for ; m != nil; m = m.parent {
+		if m == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *module) depth() int {
+	var depth int
+	for ; m != nil; m = m.parent {
+		depth++
+	}
+	return depth
+}
+
+// visibleTo checks whether the dependency is visible to consumers of the module, the nil module means
+// consumers outside of any modules.
+func (d *dependency) visibleTo(consumer *module) bool {
+	for m := d.module; m != nil; m = m.parent {
+		if consumer.within(m) {
+			return true
+		}
+		if !m.exported(d.Type) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameAs checks whether the dependencies have the same name and visible to each other.
+func (d *dependency) sameAs(o *dependency) bool {
+	return d.Var == o.Var && d.visibleTo(o.module) && o.visibleTo(d.module)
+}
+
+// closest returns dependencies visible to the module, dependencies of the module and it's closer ancestor
+// come first.
+func closest(deps []*dependency, consumer *module) []*dependency {
+	var hasModule bool
+	for _, d := range deps {
+		if d.module != nil {
+			hasModule = true
+			break
+		}
+	}
+	if !hasModule {
+		return deps
+	}
+
+	var (
+		visible = make([]*dependency, 0, len(deps))
+		depths  = make(map[*dependency]int, len(deps))
+	)
+	for _, d := range deps {
+		if !d.visibleTo(consumer) {
+			continue
+		}
+		visible = append(visible, d)
+		if consumer.within(d.module) {
+			depths[d] = d.module.depth()
+		}
+	}
+	sort.SliceStable(visible, func(i, j int) bool {
+		return depths[visible[i]] > depths[visible[j]]
+	})
+	return visible
+}
+
+func (j *Injector) provideModule(spec *ModuleSpec, parent *module) error {
+	m := &module{
+		spec:   spec,
+		path:   spec.name,
+		parent: parent,
+	}
+	if parent != nil {
+		m.path = parent.path + "/" + spec.name
+	}
+	if spec.err != nil {
+		return fmt.Errorf("module %s: %w", m.path, spec.err)
+	}
+	for _, arg := range spec.providers {
+		o := parseOptionValue(arg)
+		o.module = m
+		err := j.provideOption(o)
+		if err != nil {
+			// errors of sub-modules are already wrapped.
+			if _, ok := o.moduleSpec(); ok {
+				return err
+			}
+			return fmt.Errorf("module %s: %w", m.path, err)
+		}
+	}
+	return nil
+}
+
+func (o optionValue) moduleSpec() (*ModuleSpec, bool) {
+	if !o.Value.IsValid() || !o.Value.CanInterface() {
+		return nil, false
+	}
+	spec, ok := o.Value.Interface().(*ModuleSpec)
+	return spec, ok
+}
+
+// apply bind the provider and it's dependencies to the module.
+func (m *module) apply(p *provider) {
+	if p.name != "" {
+		p.name = m.path + "/" + p.name
+	}
+	for _, d := range p.deps {
+		d.module = m
+	}
+	for _, d := range p.provides {
+		d.module = m
+	}
+}
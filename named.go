@@ -0,0 +1,81 @@
+package di
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// namedLocal find named values of the element type provided by the injector itself.
+func (j *Injector) namedLocal(d *dependency) []*dependency {
+	var mods []*dependency
+	for _, m := range j.deps[d.Type.Elem()] {
+		if m.Var != "" && m.visibleTo(d.module) {
+			mods = append(mods, m)
+		}
+	}
+	return mods
+}
+
+// named find named values of the element type provided by the injector and the parent injectors, values
+// of the injector shadow values of the parent injectors with the same name.
+func (j *Injector) named(d *dependency) map[string]*dependency {
+	var mods map[string]*dependency
+	if j.parent != nil {
+		mods = j.parent.named(d)
+	}
+	for _, m := range j.namedLocal(d) {
+		if mods == nil {
+			mods = make(map[string]*dependency)
+		}
+		mods[m.Var] = m
+	}
+	return mods
+}
+
+type namedMapParser struct {
+	dep *dependency
+}
+
+func (n namedMapParser) Parse(r *resolution) (reflect.Value, error) {
+	mods := r.injector.named(n.dep)
+	if len(mods) == 0 && !n.dep.Optional {
+		return reflect.Value{}, n.dep.notExistError("")
+	}
+	v := reflect.MakeMapWithSize(n.dep.Type, len(mods))
+	for name, m := range mods {
+		val, err := r.load(m)
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		if !val.IsValid() {
+			return reflect.Value{}, m.notInitializedError("")
+		}
+		v.SetMapIndex(reflect.ValueOf(name).Convert(n.dep.Type.Key()), val)
+	}
+	return v, nil
+}
+
+func (n namedMapParser) Resolve(r *resolution, v reflect.Value) error {
+	return nil
+}
+
+func (n namedMapParser) Inject(v reflect.Value, r *resolution) error {
+	val, err := n.Parse(r)
+	if err != nil {
+		return err
+	}
+	v.Set(val)
+	return nil
+}
+
+func newNamedMapParser(t reflect.Type, tag fieldTag) (*dependency, depTool, error) {
+	if t.Kind() != reflect.Map || t.Key().Kind() != reflect.String {
+		return nil, nil, fmt.Errorf("named dependencies must be received by map with string key: %s", t.String())
+	}
+	d := &dependency{
+		Type:     t,
+		Map:      true,
+		Optional: tag.Optional,
+	}
+	return d, namedMapParser{dep: d}, nil
+}
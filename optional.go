@@ -0,0 +1,55 @@
+package di
+
+import (
+	"reflect"
+)
+
+var optionalReftype = reflect.TypeOf((*optionalType)(nil)).Elem()
+
+type optionalType interface {
+	optionalElem() reflect.Type
+}
+
+// Optional wraps a dependency that may not be provided, Valid reports whether it's provided, and Value is
+// the zero value if it's not.
+// It's available for function parameters, structure fields and Inject destinations.
+type Optional[T any] struct {
+	Value T
+	Valid bool
+}
+
+func (Optional[T]) optionalElem() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+type optionalParser struct {
+	dep *dependency
+	typ reflect.Type
+}
+
+func (o optionalParser) Parse(r *resolution) (reflect.Value, error) {
+	v := reflect.New(o.typ).Elem()
+	if r.match(o.dep) == nil {
+		return v, nil
+	}
+	val, err := o.dep.Parse(r)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	v.Field(0).Set(val)
+	v.Field(1).SetBool(true)
+	return v, nil
+}
+
+func (o optionalParser) Resolve(r *resolution, v reflect.Value) error {
+	return nil
+}
+
+func (o optionalParser) Inject(v reflect.Value, r *resolution) error {
+	val, err := o.Parse(r)
+	if err != nil {
+		return err
+	}
+	v.Set(val)
+	return nil
+}
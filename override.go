@@ -0,0 +1,82 @@
+package di
+
+// OptOverride indicates that values provided by the value/function replace the existing dependencies have
+// the same type and name instead of conflicting with them, decorators of the existing dependencies are kept.
+// A function provider whose values are all replaced will not be run. It should be used before Run.
+func OptOverride(v interface{}) interface{} {
+	o := parseOptionValue(v)
+	o.Override = true
+	return o
+}
+
+// Replace is same as Provide except that all values are wrapped by OptOverride, it's helpful for swapping
+// in fakes in tests.
+func (j *Injector) Replace(v ...interface{}) error {
+	overrides := make([]interface{}, 0, len(v))
+	for _, arg := range v {
+		overrides = append(overrides, OptOverride(arg))
+	}
+	return j.Provide(overrides...)
+}
+
+// replaceDependency replace the dependency has the same name with mod, mod is appended if there is no
+// such dependency.
+func (j *Injector) replaceDependency(mods []*dependency, mod *dependency) []*dependency {
+	for i, m := range mods {
+		if !m.sameAs(mod) {
+			continue
+		}
+		m.replaced = true
+		mod.decorators = append(m.decorators, mod.decorators...)
+		m.decorators = nil
+		mods[i] = mod
+		j.removeReplaced(m.Provider)
+		return mods
+	}
+	return append(mods, mod)
+}
+
+// removeReplaced remove the provider if all of it's values are replaced.
+func (j *Injector) removeReplaced(p *provider) {
+	for _, d := range p.provides {
+		if d.Group != "" || !d.replaced {
+			return
+		}
+	}
+	for i, prov := range j.providers {
+		if prov == p {
+			j.providers = append(j.providers[:i], j.providers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Clone create a new injector with the same parent, settings, providers and decorators, function providers
+// are not shared, they will be run again by the new injector. Values of overrides replace the existing
+// dependencies like Replace, it's helpful for tests to swap in fakes without rebuilding the whole graph.
+func (j *Injector) Clone(overrides ...interface{}) (*Injector, error) {
+	j.mu.RLock()
+	provided := append([]interface{}(nil), j.provided...)
+	j.mu.RUnlock()
+	j.pendingMu.Lock()
+	provided = append(provided, j.pendingProviders...)
+	j.pendingMu.Unlock()
+
+	c := &Injector{
+		parent:           j.parent,
+		deps:             make(dependencies),
+		runner:           j.runner,
+		logger:           j.logger,
+		profiles:         j.profiles,
+		interfaceBinding: j.interfaceBinding,
+	}
+	err := c.Provide(provided...)
+	if err != nil {
+		return nil, err
+	}
+	err = c.Replace(overrides...)
+	if err != nil {
+		return nil, err
+	}
+	return c, nil
+}
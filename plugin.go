@@ -1,7 +1,7 @@
 package di
 
 import (
-	"fmt"
+	"context"
 	"log"
 	"runtime"
 	"sync"
@@ -18,133 +18,165 @@ type nopLogger struct{}
 func (nopLogger) Begin(name string, at time.Time)                  {}
 func (nopLogger) End(name string, at time.Time, dur time.Duration) {}
 
+// Task is a provider scheduled by the injector in Run.
+type Task interface {
+	// Name returns the provider name.
+	Name() string
+	// Provides returns dependencies provided by the provider.
+	Provides() []string
+	// Dependencies returns tasks of the same run which must be finished before the task.
+	Dependencies() []Task
+	// Ready returns a channel which is closed when all dependencies are finished or failed.
+	Ready() <-chan struct{}
+	// Run run the provider, it should be called after the task is ready. If any dependency failed or the
+	// context of Run is canceled, the provider is skipped and nil is returned. The error of the provider is
+	// wrapped by ProviderError, and panics in running, including loggers, are recovered as PanicError.
+	Run() error
+}
+
+// Runner schedules tasks in Run, Schedule is called for each task in topological order of the dependency
+// graph, then Wait is called after all tasks are scheduled, the runner may be reused by next Run.
 type Runner interface {
-	run(j *Injector, p *provider, fn func() error) error
-	waitDone() error
+	Schedule(ctx context.Context, t Task) error
+	Wait() error
+}
+
+// runnerFactory is implemented by built-in runners to create a new runner with the same settings.
+type runnerFactory interface {
+	newRunner() Runner
 }
 
 type syncRunner struct {
 }
 
+// SyncRunner run each task in the calling goroutine, it stops at the first error.
 func SyncRunner() Runner {
 	return syncRunner{}
 }
 
-func (syncRunner) run(j *Injector, p *provider, fn func() error) error {
-	err := fn()
-	if err != nil {
-		return fmt.Errorf("%s: %s", p.name, err.Error())
-	}
-	return nil
+func (syncRunner) Schedule(ctx context.Context, t Task) error {
+	return t.Run()
 }
-func (syncRunner) waitDone() error {
+func (syncRunner) Wait() error {
 	return nil
 }
 
+func (syncRunner) newRunner() Runner {
+	return syncRunner{}
+}
+
+func runTask(ctx context.Context, t Task) error {
+	select {
+	case <-t.Ready():
+	case <-ctx.Done():
+		return nil
+	}
+	return t.Run()
+}
+
 type asyncRunner struct {
-	wg     sync.WaitGroup
-	errors providerErrors
+	wg sync.WaitGroup
 
-	mu            sync.Mutex
-	providerDones map[*provider]chan struct{}
-	closeCh       chan struct{}
+	mu     sync.Mutex
+	errors providerErrors
 }
 
+// AsyncRunner run each task in a new goroutine once it's ready, all errors are collected.
 func AsyncRunner() Runner {
-	return &asyncRunner{
-		providerDones: make(map[*provider]chan struct{}),
-	}
+	return &asyncRunner{}
 }
 
-func (a *asyncRunner) getOrCreateDoneCh(p *provider) chan struct{} {
-	c, has := a.providerDones[p]
-	if !has {
-		c = make(chan struct{})
-		a.providerDones[p] = c
-	}
-	return c
+func (a *asyncRunner) newRunner() Runner {
+	return &asyncRunner{}
 }
 
-func (a *asyncRunner) providerDoneCh(p *provider) chan struct{} {
+func (a *asyncRunner) Schedule(ctx context.Context, t Task) error {
+	a.wg.Add(1)
+	go func() {
+		defer a.wg.Done()
+
+		err := runTask(ctx, t)
+		if err != nil {
+			a.mu.Lock()
+			a.errors.Add(err)
+			a.mu.Unlock()
+		}
+	}()
+	return nil
+}
+
+func (a *asyncRunner) Wait() error {
+	a.wg.Wait()
 	a.mu.Lock()
-	c := a.getOrCreateDoneCh(p)
+	err := a.errors.ToError()
+	a.errors = providerErrors{}
 	a.mu.Unlock()
+	return err
+}
+
+type poolRunner struct {
+	size int
+	wg   sync.WaitGroup
 
-	return c
+	tasks chan poolTask
+
+	mu     sync.Mutex
+	errors providerErrors
 }
 
-func (a *asyncRunner) doCloseCh(ch chan struct{}) {
-	select {
-	case <-ch:
-		return
-	default:
-		close(ch)
-	}
+type poolTask struct {
+	ctx  context.Context
+	task Task
 }
 
-func (a *asyncRunner) finishProvider(p *provider, err error) {
-	a.mu.Lock()
-	c := a.getOrCreateDoneCh(p)
-	if err == nil {
-		close(c)
-	} else {
-		a.doCloseCh(a.closeCh)
-		close(c)
-		a.errors.Append(p.name, err)
+// PoolRunner run tasks by at most size goroutines, tasks are taken by workers in the scheduled order, so
+// a worker only waits for tasks have been taken by other workers.
+func PoolRunner(size int) Runner {
+	if size <= 0 {
+		size = runtime.NumCPU()
 	}
-	a.mu.Unlock()
+	return &poolRunner{size: size}
 }
 
-func (a *asyncRunner) run(j *Injector, p *provider, fn func() error) error {
-	a.wg.Add(1)
+func (p *poolRunner) newRunner() Runner {
+	return &poolRunner{size: p.size}
+}
 
-	a.mu.Lock()
-	if a.closeCh == nil {
-		a.closeCh = make(chan struct{})
-	}
-	a.mu.Unlock()
-	go func() {
-		defer func() {
-			if err := recover(); err != nil {
-				buf := make([]byte, 4096)
-				n := runtime.Stack(buf, false)
-				e := fmt.Errorf("panic: %v, stack: %s", err, string(buf[:n]))
-				a.finishProvider(p, e)
-			}
-
-			a.wg.Done()
-		}()
-
-		for _, dep := range p.deps {
-			dp := j.deps.match(dep)
-			if dp == nil || dp.Provider == nil {
-				a.finishProvider(p, dep.notExistError(p.name))
-				return
-			}
-			select {
-			case <-a.providerDoneCh(dp.Provider):
-			case <-a.closeCh:
-				return
-			}
+func (p *poolRunner) worker(tasks <-chan poolTask) {
+	defer p.wg.Done()
+
+	for t := range tasks {
+		err := runTask(t.ctx, t.task)
+		if err != nil {
+			p.mu.Lock()
+			p.errors.Add(err)
+			p.mu.Unlock()
 		}
+	}
+}
 
-		a.finishProvider(p, fn())
-	}()
+func (p *poolRunner) Schedule(ctx context.Context, t Task) error {
+	if p.tasks == nil {
+		p.tasks = make(chan poolTask)
+		p.wg.Add(p.size)
+		for i := 0; i < p.size; i++ {
+			go p.worker(p.tasks)
+		}
+	}
+	p.tasks <- poolTask{ctx: ctx, task: t}
 	return nil
 }
 
-func (a *asyncRunner) waitDone() error {
-	a.wg.Wait()
-	a.mu.Lock()
-	if a.closeCh != nil {
-		a.doCloseCh(a.closeCh)
-		a.closeCh = nil
+func (p *poolRunner) Wait() error {
+	if p.tasks != nil {
+		close(p.tasks)
+		p.tasks = nil
 	}
-	for _, c := range a.providerDones {
-		a.doCloseCh(c)
-	}
-	err := a.errors.ToError()
-	a.mu.Unlock()
+	p.wg.Wait()
+	p.mu.Lock()
+	err := p.errors.ToError()
+	p.errors = providerErrors{}
+	p.mu.Unlock()
 	return err
 }
 
@@ -0,0 +1,67 @@
+package di
+
+import (
+	"strings"
+)
+
+// OptWhen indicates that the value/function is provided only if the condition returns true, the condition
+// is evaluated when it's passed to Provide. Multiple conditions must be all satisfied.
+func OptWhen(cond func() bool, v interface{}) interface{} {
+	o := parseOptionValue(v)
+	o.Conditions = append(o.Conditions, cond)
+	return o
+}
+
+// OptProfile indicates that the value/function is provided only if the profile is active, if it's wrapped
+// multiple times, any of the profiles is active is enough. Active profiles are specified by
+// Injector.UseProfiles, or the environment variable "DI_PROFILES" separated by comma for NewAndParseEnv.
+func OptProfile(profile string, v interface{}) interface{} {
+	o := parseOptionValue(v)
+	o.Profiles = append(o.Profiles, profile)
+	return o
+}
+
+// UseProfiles set active profiles for OptProfile, it should be called before Provide. The child injector
+// inherits profiles.
+func (j *Injector) UseProfiles(profiles ...string) *Injector {
+	j.profiles = profiles
+	return j
+}
+
+func parseProfiles(s string) []string {
+	var profiles []string
+	for _, p := range strings.Split(s, ",") {
+		p = strings.TrimSpace(p)
+		if p != "" {
+			profiles = append(profiles, p)
+		}
+	}
+	return profiles
+}
+
+func (j *Injector) profileActive(profile string) bool {
+	for _, p := range j.profiles {
+		if p == profile {
+			return true
+		}
+	}
+	return false
+}
+
+// enabled checks whether conditions and profiles of the option are satisfied.
+func (j *Injector) enabled(o optionValue) bool {
+	for _, cond := range o.Conditions {
+		if !cond() {
+			return false
+		}
+	}
+	if len(o.Profiles) == 0 {
+		return true
+	}
+	for _, p := range o.Profiles {
+		if j.profileActive(p) {
+			return true
+		}
+	}
+	return false
+}
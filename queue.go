@@ -1,13 +1,16 @@
 package di
 
 import (
-	"fmt"
+	"context"
+	"runtime/debug"
 	"sort"
 	"sync"
+	"sync/atomic"
 )
 
 type providerDones struct {
 	dones map[*provider]struct{}
+	order []*provider
 	mu    sync.RWMutex
 }
 
@@ -24,19 +27,30 @@ func (p *providerDones) markDone(prov *provider) {
 		p.dones = make(map[*provider]struct{})
 	}
 	p.dones[prov] = struct{}{}
+	p.order = append(p.order, prov)
 	p.mu.Unlock()
 }
 
+func (p *providerDones) clearOrder() []*provider {
+	p.mu.Lock()
+	order := p.order
+	p.order = nil
+	p.mu.Unlock()
+	return order
+}
+
 type queueNode struct {
 	provider *provider
+	parents  []*provider
 	weight   int
 
 	parentDone bool
 }
 
 type queue struct {
-	deps  dependencies
-	nodes []*queueNode
+	injector *Injector
+	nodes    []*queueNode
+	visiting map[*provider]bool
 }
 
 func (q *queue) search(p *provider) *queueNode {
@@ -68,30 +82,103 @@ func (q *queue) add(p *provider, context []string, dones *providerDones) (*queue
 			return node, nil
 		}
 		context = append(context, p.name)
-		return nil, fmt.Errorf("cycle dependencies: %v", context)
+		return nil, &CycleError{Path: context}
 	}
 
 	context = append(context, p.name)
 	node = q.append(p)
-	var (
-		parent *queueNode
-		err    error
-	)
+	err := q.addDeps(node, p, context, dones)
+	if err != nil {
+		return nil, err
+	}
+	node.parentDone = true
+	return node, nil
+}
+
+// addDeps add providers of the dependencies as parents of the node, transient providers are not
+// scheduled, the node depends on their dependencies instead.
+func (q *queue) addDeps(node *queueNode, p *provider, context []string, dones *providerDones) error {
+	for _, mod := range q.injector.decorated(p) {
+		err := q.addDep(node, mod, mod, context, dones)
+		if err != nil {
+			return err
+		}
+	}
 	for _, dep := range p.deps {
-		mod := q.deps.match(dep)
+		if dep.Group != "" {
+			for _, mod := range q.injector.groupLocal(dep) {
+				err := q.addDep(node, dep, mod, context, dones)
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if dep.Map {
+			for _, mod := range q.injector.namedLocal(dep) {
+				err := q.addDep(node, dep, mod, context, dones)
+				if err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if dep.Lazy {
+			continue
+		}
+		mod := q.injector.matchLocal(dep)
 		if mod == nil {
-			return nil, dep.notExistError(p.name)
+			if q.injector.parent != nil {
+				mod = q.injector.parent.match(dep)
+			}
+			if mod == nil {
+				if dep.Optional {
+					continue
+				}
+				return q.injector.matchError(dep, p.name)
+			}
+			// providers of parent injector are run by itself.
+			if mod.Provider.lifetime == lifetimeScoped {
+				return &ScopeError{Dependency: dep.String(), Provider: node.provider.name}
+			}
+			continue
 		}
-		parent, err = q.add(mod.Provider, context, dones)
+		err := q.addDep(node, dep, mod, context, dones)
 		if err != nil {
-			return nil, err
+			return err
+		}
+	}
+	return nil
+}
+
+func (q *queue) addDep(node *queueNode, dep, mod *dependency, context []string, dones *providerDones) error {
+	dp := mod.Provider
+	switch dp.lifetime {
+	case lifetimeScoped:
+		return &ScopeError{Dependency: dep.String(), Provider: node.provider.name}
+	case lifetimeTransient:
+		if q.visiting[dp] {
+			return &CycleError{Path: append(context, dp.name)}
+		}
+		if q.visiting == nil {
+			q.visiting = make(map[*provider]bool)
+		}
+		q.visiting[dp] = true
+		err := q.addDeps(node, dp, append(context, dp.name), dones)
+		delete(q.visiting, dp)
+		return err
+	}
+	for _, dp := range mod.providers(node.provider) {
+		parent, err := q.add(dp, context, dones)
+		if err != nil {
+			return err
 		}
 		if parent != nil {
 			node.weight += parent.weight
+			node.parents = append(node.parents, parent.provider)
 		}
 	}
-	node.parentDone = true
-	return node, nil
+	return nil
 }
 
 func (q *queue) Len() int {
@@ -106,19 +193,155 @@ func (q *queue) Swap(i, j int) {
 	q.nodes[i], q.nodes[j] = q.nodes[j], q.nodes[i]
 }
 
-func newQueue(providers []*provider, mods dependencies, dones *providerDones) ([]*queueNode, error) {
+// newQueue build the queue of providers need to be run for the roots, roots required only lazily are
+// skipped. Singleton providers not in the queue are deferred until they are firstly required.
+func newQueue(j *Injector, roots []*provider, dones *providerDones) ([]*queueNode, error) {
 	var (
 		queue = queue{
-			deps: mods,
+			injector: j,
 		}
-		err error
+		err    error
+		lazies = make(map[*provider]bool)
 	)
-	for _, p := range providers {
+	for _, p := range j.providers {
+		for _, dep := range p.deps {
+			if !dep.Lazy {
+				continue
+			}
+			if mod := j.matchLocal(dep); mod != nil {
+				lazies[mod.Provider] = true
+			}
+		}
+	}
+	for _, p := range roots {
+		if p.lifetime != lifetimeSingleton || lazies[p] {
+			continue
+		}
 		_, err = queue.add(p, nil, dones)
 		if err != nil {
 			return nil, err
 		}
 	}
+	for _, p := range j.providers {
+		if p.lifetime == lifetimeSingleton && !dones.isDone(p) {
+			p.deferred = queue.search(p) == nil
+		}
+	}
 	sort.Sort(&queue)
 	return queue.nodes, nil
 }
+
+func removeNode(nodes []*queueNode, p *provider) []*queueNode {
+	for i, n := range nodes {
+		if n.provider == p {
+			return append(nodes[:i], nodes[i+1:]...)
+		}
+	}
+	return nodes
+}
+
+type task struct {
+	ctx  context.Context
+	node *queueNode
+	fn   func() error
+
+	deps     []*task
+	children []*task
+	pending  int32
+	ready    chan struct{}
+	failed   uint32
+}
+
+func newTasks(ctx context.Context, nodes []*queueNode, fn func(t *task) error) []*task {
+	var (
+		tasks = make([]*task, 0, len(nodes))
+		index = make(map[*provider]*task, len(nodes))
+	)
+	for _, n := range nodes {
+		t := &task{
+			ctx:   ctx,
+			node:  n,
+			ready: make(chan struct{}),
+		}
+		t.fn = func() error {
+			return fn(t)
+		}
+		for _, parent := range n.parents {
+			dep, has := index[parent]
+			if !has {
+				continue
+			}
+			t.deps = append(t.deps, dep)
+			dep.children = append(dep.children, t)
+		}
+		t.pending = int32(len(t.deps))
+		if t.pending == 0 {
+			close(t.ready)
+		}
+		index[n.provider] = t
+		tasks = append(tasks, t)
+	}
+	return tasks
+}
+
+func (t *task) Name() string {
+	return t.node.provider.name
+}
+
+func (t *task) Provides() []string {
+	return t.node.provider.provideNames()
+}
+
+func (t *task) Dependencies() []Task {
+	deps := make([]Task, 0, len(t.deps))
+	for _, d := range t.deps {
+		deps = append(deps, d)
+	}
+	return deps
+}
+
+func (t *task) Ready() <-chan struct{} {
+	return t.ready
+}
+
+func (t *task) finish(failed bool) {
+	if failed {
+		atomic.StoreUint32(&t.failed, 1)
+	}
+	for _, c := range t.children {
+		if atomic.AddInt32(&c.pending, -1) == 0 {
+			close(c.ready)
+		}
+	}
+}
+
+func (t *task) Run() (err error) {
+	for _, d := range t.deps {
+		if atomic.LoadUint32(&d.failed) != 0 {
+			t.finish(true)
+			return nil
+		}
+	}
+	if t.ctx.Err() != nil {
+		t.finish(true)
+		return nil
+	}
+
+	finished := false
+	defer func() {
+		// panics out of the provider function, such as in loggers, are recovered too.
+		if e := recover(); e != nil {
+			err = &ProviderError{Provider: t.Name(), Err: &PanicError{Provider: t.Name(), Value: e, Stack: debug.Stack()}}
+		}
+		if !finished {
+			t.finish(true)
+		}
+	}()
+	err = t.fn()
+	finished = true
+	t.finish(err != nil)
+	if err != nil {
+		return &ProviderError{Provider: t.Name(), Err: err}
+	}
+	return nil
+}
@@ -0,0 +1,132 @@
+package di
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+type providerTiming struct {
+	scheduled  time.Time
+	begin, end time.Time
+	parents    []*provider
+}
+
+func (t *providerTiming) schedule(parents []*provider) {
+	t.scheduled = time.Now()
+	t.parents = parents
+}
+
+// ProviderReport is the timing of a function provider finished in Run, Start is the offset from the
+// beginning of Run, Wait is the duration from it's scheduled to it's started, it's mostly waiting for
+// dependencies under AsyncRunner, and Duration is the execution time including start hooks.
+type ProviderReport struct {
+	Name     string
+	Start    time.Duration
+	Wait     time.Duration
+	Duration time.Duration
+	Critical bool
+}
+
+// Report is the startup timing of the injector, CriticalPath is the chain of providers determined the total
+// latency, each provider is the dependency finished last of the next one.
+type Report struct {
+	Total        time.Duration
+	Providers    []ProviderReport
+	CriticalPath []string
+}
+
+// Report returns the timing report of function providers finished in Run, providers are sorted by their
+// start time.
+func (j *Injector) Report() *Report {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	var (
+		timings = make(map[*provider]providerTiming)
+		begin   time.Time
+		last    *provider
+	)
+	for _, p := range j.providers {
+		if !p.fn.IsValid() || !j.dones.isDone(p) {
+			continue
+		}
+		p.mu.Lock()
+		t := p.timing
+		p.mu.Unlock()
+		if t.scheduled.IsZero() || t.scheduled.After(t.begin) {
+			t.scheduled = t.begin
+		}
+		timings[p] = t
+		if begin.IsZero() || t.scheduled.Before(begin) {
+			begin = t.scheduled
+		}
+		if last == nil || t.end.After(timings[last].end) {
+			last = p
+		}
+	}
+
+	var (
+		report   Report
+		critical = make(map[*provider]bool)
+	)
+	if last != nil {
+		report.Total = timings[last].end.Sub(begin)
+	}
+	for p := last; p != nil; {
+		critical[p] = true
+		report.CriticalPath = append(report.CriticalPath, p.name)
+		var next *provider
+		for _, parent := range timings[p].parents {
+			t, has := timings[parent]
+			if has && !critical[parent] && (next == nil || t.end.After(timings[next].end)) {
+				next = parent
+			}
+		}
+		p = next
+	}
+	for i, l := 0, len(report.CriticalPath); i < l/2; i++ {
+		report.CriticalPath[i], report.CriticalPath[l-1-i] = report.CriticalPath[l-1-i], report.CriticalPath[i]
+	}
+
+	for p, t := range timings {
+		report.Providers = append(report.Providers, ProviderReport{
+			Name:     p.name,
+			Start:    t.begin.Sub(begin),
+			Wait:     t.begin.Sub(t.scheduled),
+			Duration: t.end.Sub(t.begin),
+			Critical: critical[p],
+		})
+	}
+	sort.Slice(report.Providers, func(i, j int) bool {
+		a, b := report.Providers[i], report.Providers[j]
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		return a.Name < b.Name
+	})
+	return &report
+}
+
+// WriteTable write the report as a table, providers on the critical path are marked by "*".
+func (r *Report) WriteTable(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "PROVIDER\tSTART\tWAIT\tDURATION\tCRITICAL")
+	for _, p := range r.Providers {
+		var critical string
+		if p.Critical {
+			critical = "*"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", p.Name, p.Start, p.Wait, p.Duration, critical)
+	}
+	fmt.Fprintf(tw, "TOTAL\t\t\t%s\t\n", r.Total)
+	err := tw.Flush()
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "critical path: %s\n", strings.Join(r.CriticalPath, " -> "))
+	return err
+}
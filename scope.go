@@ -0,0 +1,321 @@
+package di
+
+import (
+	"context"
+	"reflect"
+	"sync"
+)
+
+type lifetime int
+
+const (
+	lifetimeSingleton lifetime = iota
+	lifetimeTransient
+	lifetimeScoped
+)
+
+func (l lifetime) String() string {
+	switch l {
+	case lifetimeTransient:
+		return "transient"
+	case lifetimeScoped:
+		return "scoped"
+	default:
+		return "singleton"
+	}
+}
+
+// Scope holds values of scoped providers, it's created by Injector.NewScope, typically for each request.
+// Singleton values are shared with the injector, scoped providers are run at most once for each scope
+// when they are firstly required, and transient providers are run for each resolution.
+type Scope struct {
+	injector *Injector
+
+	mu     sync.Mutex
+	values map[*dependency]reflect.Value
+	order  []*dependency
+}
+
+// NewScope create a scope of the injector, it should be called after running the injector.
+func (j *Injector) NewScope() *Scope {
+	return &Scope{
+		injector: j,
+		values:   make(map[*dependency]reflect.Value),
+	}
+}
+
+// Inject inject dependency values to destination pointers, scoped and transient providers will be run
+// if necessary.
+// Available option functions: all of OptDecompose, OptNamed.
+func (s *Scope) Inject(v ...interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	j := s.injector
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+	for _, p := range v {
+		err := j.inject(p, j.newResolution(s))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resolution resolves dependency values for a single provider running or Inject destination, values of
+// singleton providers are stored in the dependencies, values of transient providers are cached in the
+// resolution, and values of scoped providers are cached in the scope.
+type resolution struct {
+	ctx        context.Context
+	injector   *Injector
+	scope      *Scope
+	transients map[*dependency]reflect.Value
+	running    []*provider
+	onDemand   bool
+	exec       *executor
+	timed      *timedHooks
+}
+
+func (j *Injector) newResolution(s *Scope) *resolution {
+	return &resolution{
+		injector: j,
+		scope:    s,
+		exec:     &executor{},
+	}
+}
+
+// private create a resolution shares nothing mutable with r except the scope, it's used by providers run
+// in other goroutines.
+func (r *resolution) private() *resolution {
+	p := &resolution{
+		ctx:      r.ctx,
+		injector: r.injector,
+		scope:    r.scope,
+		onDemand: r.onDemand,
+		exec:     r.exec,
+	}
+	p.running = append(p.running, r.running...)
+	return p
+}
+
+func (r *resolution) context() context.Context {
+	if r.ctx == nil {
+		return context.Background()
+	}
+	return r.ctx
+}
+
+func (r *resolution) match(d *dependency) *dependency {
+	return r.injector.match(d)
+}
+
+func (r *resolution) values(m *dependency) (map[*dependency]reflect.Value, error) {
+	p := m.Provider
+	if p == nil || p.lifetime == lifetimeSingleton {
+		return nil, nil
+	}
+	if p.lifetime == lifetimeScoped {
+		if r.scope == nil {
+			return nil, &ScopeError{Dependency: m.String()}
+		}
+		return r.scope.values, nil
+	}
+	if r.transients == nil {
+		r.transients = make(map[*dependency]reflect.Value)
+	}
+	return r.transients, nil
+}
+
+func (r *resolution) load(m *dependency) (reflect.Value, error) {
+	values, err := r.values(m)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if values == nil {
+		p := m.Provider
+		if p == nil || (!p.deferred && !r.onDemand) || !r.injector.owns(p) {
+			return m.Val, nil
+		}
+		// deferred singleton providers are run when firstly required, and all of singleton providers are
+		// run on demand by Lazy, providers already run by others are skipped by execute.
+		for _, p := range m.providers(r.current()) {
+			if r.injector.dones.isDone(p) {
+				continue
+			}
+			err = r.runOnce(p, func() error {
+				return r.injector.execute(p, r)
+			})
+			if err != nil {
+				return reflect.Value{}, err
+			}
+		}
+		return m.Val, nil
+	}
+	if v, has := values[m]; has {
+		return v, nil
+	}
+
+	p := m.Provider
+	err = r.runOnce(p, func() error {
+		return r.injector.runProvider(p, r)
+	})
+	return values[m], err
+}
+
+// current returns the provider is running by the resolution.
+func (r *resolution) current() *provider {
+	if len(r.running) == 0 {
+		return nil
+	}
+	return r.running[len(r.running)-1]
+}
+
+// runOnce run the function for the provider, the provider is recorded in the resolution stack while
+// running, so the full path of cycle dependencies is reported.
+func (r *resolution) runOnce(p *provider, fn func() error) error {
+	for i, running := range r.running {
+		if running == p {
+			path := make([]string, 0, len(r.running)-i+1)
+			for _, rp := range r.running[i:] {
+				path = append(path, rp.name)
+			}
+			return &CycleError{Path: append(path, p.name)}
+		}
+	}
+	r.running = append(r.running, p)
+	err := fn()
+	r.running = r.running[:len(r.running)-1]
+	if err != nil {
+		return &ProviderError{Provider: p.name, Err: err}
+	}
+	return nil
+}
+
+// executor identifies resolutions executing singleton providers in the same chain, resolutions of Lazy values
+// are executed by child executors of the executor injected the Lazy, because the provider received the Lazy
+// usually waits for Get.
+type executor struct {
+	parent *executor
+}
+
+func (e *executor) within(ancestor *executor) bool {
+	for ; e != nil; e = e.parent {
+		if e == ancestor {
+			return true
+		}
+	}
+	return false
+}
+
+// providerLocks records executors own and wait for singleton providers, so waiting for providers locked by
+// executors waiting for each other across goroutines is reported as CycleError instead of deadlock.
+type providerLocks struct {
+	mu      sync.Mutex
+	owners  map[*provider]*executor
+	waiting map[*executor]*provider
+}
+
+// lock lock the provider for the executor, it fails if the provider is owned by the executor or it's
+// ancestors, or by executors waiting for them directly or indirectly.
+func (l *providerLocks) lock(p *provider, e *executor) error {
+	l.mu.Lock()
+	chain := []*provider{p}
+	for q := p; ; {
+		owner := l.owners[q]
+		if owner == nil {
+			break
+		}
+		if e.within(owner) {
+			l.mu.Unlock()
+			path := []string{q.name}
+			for _, c := range chain {
+				path = append(path, c.name)
+			}
+			return &CycleError{Path: path}
+		}
+		q = l.waitedBy(owner)
+		if q == nil || containsProvider(chain, q) {
+			break
+		}
+		chain = append(chain, q)
+	}
+	if l.waiting == nil {
+		l.waiting = make(map[*executor]*provider)
+		l.owners = make(map[*provider]*executor)
+	}
+	l.waiting[e] = p
+	l.mu.Unlock()
+
+	p.mu.Lock()
+	l.mu.Lock()
+	delete(l.waiting, e)
+	l.owners[p] = e
+	l.mu.Unlock()
+	return nil
+}
+
+// waitedBy returns the provider waited by the executor or it's child executors.
+func (l *providerLocks) waitedBy(e *executor) *provider {
+	for w, p := range l.waiting {
+		if w.within(e) {
+			return p
+		}
+	}
+	return nil
+}
+
+func containsProvider(providers []*provider, p *provider) bool {
+	for _, prov := range providers {
+		if prov == p {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *providerLocks) unlock(p *provider) {
+	l.mu.Lock()
+	delete(l.owners, p)
+	l.mu.Unlock()
+	p.mu.Unlock()
+}
+
+func (r *resolution) store(m *dependency, v reflect.Value) error {
+	values, err := r.values(m)
+	if err != nil {
+		return err
+	}
+	if values == nil {
+		m.Val = v
+	} else {
+		values[m] = v
+		if m.Provider.lifetime == lifetimeScoped {
+			r.scope.order = append(r.scope.order, m)
+		}
+	}
+	return nil
+}
+
+// Close stop values created by scoped providers of the scope in reverse creation order, values implements
+// Stopper or io.Closer are stopped like Shutdown. The scope could be reused after closing, scoped providers
+// will be run again.
+func (s *Scope) Close(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var (
+		errs    providerErrors
+		stopped = make(map[interface{}]bool)
+	)
+	for i := len(s.order) - 1; i >= 0; i-- {
+		m := s.order[i]
+		err := stopValue(ctx, s.values[m], stopped)
+		if err != nil {
+			errs.Append(m.Provider.name, err)
+		}
+	}
+	s.values = make(map[*dependency]reflect.Value)
+	s.order = nil
+	return errs.ToError()
+}
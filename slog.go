@@ -0,0 +1,68 @@
+//go:build go1.21
+
+package di
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+func init() {
+	newSlogLogger = func() Logger { return NewSlogLogger(nil) }
+}
+
+// SlogLogger is an EventLogger writes to the slog.Logger, Begin and EventResolve are logged at debug level,
+// failures are logged at error level and others are logged at info level.
+type SlogLogger struct {
+	logger *slog.Logger
+}
+
+// NewSlogLogger create a SlogLogger, slog.Default() is used if the logger is nil.
+func NewSlogLogger(logger *slog.Logger) *SlogLogger {
+	if logger == nil {
+		logger = slog.Default()
+	}
+	return &SlogLogger{logger: logger}
+}
+
+func (l *SlogLogger) Begin(name string, at time.Time) {
+	l.logger.Debug("provider begin", slog.String("provider", name))
+}
+
+func (l *SlogLogger) End(name string, at time.Time, dur time.Duration) {
+	l.logger.Info("provider finished", slog.String("provider", name), slog.Duration("duration", dur))
+}
+
+func (l *SlogLogger) Event(e Event) {
+	var (
+		level = slog.LevelInfo
+		attrs []slog.Attr
+	)
+	if e.Provider != "" {
+		attrs = append(attrs, slog.String("provider", e.Provider))
+	}
+	if len(e.Provides) > 0 {
+		attrs = append(attrs, slog.Any("provides", e.Provides))
+	}
+	if e.Dependency != "" {
+		attrs = append(attrs, slog.String("dependency", e.Dependency))
+	}
+	if e.Duration > 0 {
+		attrs = append(attrs, slog.Duration("duration", e.Duration))
+	}
+	if e.Err != nil {
+		level = slog.LevelError
+		attrs = append(attrs, slog.String("error", e.Err.Error()))
+	}
+	switch e.Kind {
+	case EventResolve:
+		level = slog.LevelDebug
+	case EventPanic:
+		level = slog.LevelError
+		attrs = append(attrs, slog.Any("panic", e.Value))
+	case EventPending:
+		attrs = append(attrs, slog.Int("count", e.Count))
+	}
+	l.logger.LogAttrs(context.Background(), level, "di "+e.Kind.String(), attrs...)
+}
@@ -0,0 +1,59 @@
+//go:build go1.21
+
+package di
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	d := New().UseLogger(NewSlogLogger(logger))
+	d.Provide(
+		"value",
+		func(s string, lc Lifecycle) int {
+			d.Provide(func(int) uint { return 1 })
+			lc.Append(Hook{OnStop: func(context.Context) error { return errors.New("stop failed") }})
+			return len(s)
+		},
+	)
+	if err := d.Run(); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Shutdown(context.Background()); err == nil {
+		t.Fatal("shutdown should fail")
+	}
+	d.Provide(func() (float64, error) { return 0, errors.New("failed") })
+	if err := d.Run(); err == nil {
+		t.Fatal("run should fail")
+	}
+
+	kinds := make(map[string]int)
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var record struct {
+			Msg      string   `json:"msg"`
+			Level    string   `json:"level"`
+			Provides []string `json:"provides"`
+			Error    string   `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(line), &record); err != nil {
+			t.Fatal(err)
+		}
+		kinds[record.Msg]++
+		if record.Msg == "di error" && (record.Level != "ERROR" || record.Error != "failed") {
+			t.Fatal(line)
+		}
+	}
+	for _, msg := range []string{"di provide", "di resolve", "di pending", "di shutdown", "di error", "provider finished"} {
+		if kinds[msg] == 0 {
+			t.Fatal(msg, kinds)
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package di
+
+import (
+	"encoding/json"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+type traceEvent struct {
+	Name  string            `json:"name"`
+	Phase string            `json:"ph"`
+	Time  float64           `json:"ts"`
+	Pid   int               `json:"pid"`
+	Tid   uint64            `json:"tid"`
+	Args  map[string]string `json:"args,omitempty"`
+}
+
+// TraceLogger is a Logger records spans of providers with goroutine IDs as threads, the result is written
+// in Chrome Trace Event Format, it could be opened by chrome://tracing or Perfetto to see the timeline of
+// Run. Spans of failed providers are not ended.
+type TraceLogger struct {
+	mu      sync.Mutex
+	pid     int
+	origin  time.Time
+	events  []traceEvent
+	threads map[uint64]bool
+}
+
+// NewTraceLogger create a TraceLogger, timestamps are relative to it's creation.
+func NewTraceLogger() *TraceLogger {
+	return &TraceLogger{
+		pid:     os.Getpid(),
+		origin:  time.Now(),
+		threads: make(map[uint64]bool),
+	}
+}
+
+func (t *TraceLogger) record(name, phase string, at time.Time) {
+	tid := goroutineID()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.threads[tid] {
+		t.threads[tid] = true
+		t.events = append(t.events, traceEvent{
+			Name:  "thread_name",
+			Phase: "M",
+			Pid:   t.pid,
+			Tid:   tid,
+			Args:  map[string]string{"name": "goroutine " + strconv.FormatUint(tid, 10)},
+		})
+	}
+	t.events = append(t.events, traceEvent{
+		Name:  name,
+		Phase: phase,
+		Time:  float64(at.Sub(t.origin).Nanoseconds()) / 1e3,
+		Pid:   t.pid,
+		Tid:   tid,
+	})
+}
+
+func (t *TraceLogger) Begin(name string, at time.Time) {
+	t.record(name, "B", at)
+}
+
+func (t *TraceLogger) End(name string, at time.Time, dur time.Duration) {
+	t.record(name, "E", at)
+}
+
+// WriteJSON write recorded spans in Chrome Trace Event Format.
+func (t *TraceLogger) WriteJSON(w io.Writer) error {
+	t.mu.Lock()
+	trace := struct {
+		TraceEvents     []traceEvent `json:"traceEvents"`
+		DisplayTimeUnit string       `json:"displayTimeUnit"`
+	}{
+		TraceEvents:     append([]traceEvent(nil), t.events...),
+		DisplayTimeUnit: "ms",
+	}
+	t.mu.Unlock()
+	return json.NewEncoder(w).Encode(trace)
+}
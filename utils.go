@@ -1,11 +1,9 @@
 package di
 
 import (
-	"bytes"
-	"errors"
-	"fmt"
 	"reflect"
 	"runtime"
+	"strconv"
 	"strings"
 )
 
@@ -29,18 +27,36 @@ func functionName(val reflect.Value) string {
 }
 
 type providerErrors struct {
-	buf bytes.Buffer
+	errs ProviderErrors
 }
 
 func (p *providerErrors) Append(name string, err error) {
-	if p.buf.Len() > 0 {
-		fmt.Fprintln(&p.buf)
-	}
-	fmt.Fprintf(&p.buf, "%s: %s", name, err.Error())
+	p.errs = append(p.errs, &ProviderError{Provider: name, Err: err})
 }
 func (p *providerErrors) ToError() error {
-	if p.buf.Len() > 0 {
-		return errors.New(p.buf.String())
+	if len(p.errs) > 0 {
+		return p.errs
 	}
 	return nil
 }
+
+// Add append the error, it's appended directly if it's already a ProviderError.
+func (p *providerErrors) Add(err error) {
+	if pe, ok := err.(*ProviderError); ok {
+		p.errs = append(p.errs, pe)
+	} else {
+		p.Append("", err)
+	}
+}
+
+// goroutineID parse the id of current goroutine from the stack header "goroutine N [running]:".
+func goroutineID() uint64 {
+	var buf [64]byte
+	s := string(buf[:runtime.Stack(buf[:], false)])
+	s = strings.TrimPrefix(s, "goroutine ")
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		s = s[:i]
+	}
+	id, _ := strconv.ParseUint(s, 10, 64)
+	return id
+}
@@ -0,0 +1,151 @@
+package di
+
+// Validate check the dependency graph without running any providers, it reports missing and ambiguous
+// dependencies, empty groups, scoped dependencies required by singleton providers and cycle
+// dependencies, all problems are returned together.
+func (j *Injector) Validate() error {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	var errs providerErrors
+	j.checkDeps(&errs)
+
+	graph := make(map[*provider][]*provider)
+	for _, p := range j.providers {
+		if p.lifetime == lifetimeSingleton {
+			graph[p] = j.dependsOn(p, &errs)
+		}
+	}
+	for _, cycle := range findCycles(j.providers, graph) {
+		names := make([]string, 0, len(cycle)+1)
+		for _, p := range cycle {
+			names = append(names, p.name)
+		}
+		names = append(names, cycle[0].name)
+		errs.Append(cycle[0].name, &CycleError{Path: names})
+	}
+	return errs.ToError()
+}
+
+// dependsOn returns singleton providers of the injector itself which the provider depends on directly,
+// transient providers are expanded to their dependencies. Missing dependencies are ignored, they are
+// reported by checkDeps.
+func (j *Injector) dependsOn(p *provider, errs *providerErrors) []*provider {
+	var (
+		parents  []*provider
+		visiting = map[*provider]bool{p: true}
+		path     = []string{p.name}
+		walk     func(p *provider)
+	)
+	add := func(dep, mod *dependency) {
+		dp := mod.Provider
+		switch dp.lifetime {
+		case lifetimeScoped:
+			errs.Append(p.name, &ScopeError{Dependency: dep.String(), Provider: p.name})
+		case lifetimeTransient:
+			if visiting[dp] {
+				errs.Append(p.name, &CycleError{Path: append(path, dp.name)})
+				return
+			}
+			visiting[dp] = true
+			path = append(path, dp.name)
+			walk(dp)
+			path = path[:len(path)-1]
+			delete(visiting, dp)
+		default:
+			parents = append(parents, mod.providers(p)...)
+		}
+	}
+	walk = func(p *provider) {
+		for _, dep := range p.deps {
+			switch {
+			case dep.Lazy:
+			case dep.Group != "":
+				for _, mod := range j.groupLocal(dep) {
+					add(dep, mod)
+				}
+			case dep.Map:
+				for _, mod := range j.namedLocal(dep) {
+					add(dep, mod)
+				}
+			default:
+				if mod := j.matchLocal(dep); mod != nil {
+					add(dep, mod)
+				} else if j.parent != nil {
+					if mod = j.parent.match(dep); mod != nil && mod.Provider.lifetime == lifetimeScoped {
+						add(dep, mod)
+					}
+				}
+			}
+		}
+	}
+	for _, mod := range j.decorated(p) {
+		parents = append(parents, mod.providers(p)...)
+	}
+	walk(p)
+	return parents
+}
+
+// findCycles find strongly connected components contain cycles by the Tarjan algorithm, providers are
+// visited in order so the result is stable.
+func findCycles(providers []*provider, graph map[*provider][]*provider) [][]*provider {
+	var (
+		index   int
+		indexes = make(map[*provider]int)
+		lowlink = make(map[*provider]int)
+		onStack = make(map[*provider]bool)
+		stack   []*provider
+		cycles  [][]*provider
+		connect func(p *provider)
+	)
+	connect = func(p *provider) {
+		indexes[p] = index
+		lowlink[p] = index
+		index++
+		stack = append(stack, p)
+		onStack[p] = true
+
+		selfLoop := false
+		for _, parent := range graph[p] {
+			if parent == p {
+				selfLoop = true
+			}
+			if _, has := indexes[parent]; !has {
+				connect(parent)
+				if lowlink[parent] < lowlink[p] {
+					lowlink[p] = lowlink[parent]
+				}
+			} else if onStack[parent] && indexes[parent] < lowlink[p] {
+				lowlink[p] = indexes[parent]
+			}
+		}
+		if lowlink[p] != indexes[p] {
+			return
+		}
+		var component []*provider
+		for {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[n] = false
+			component = append(component, n)
+			if n == p {
+				break
+			}
+		}
+		if len(component) > 1 || selfLoop {
+			for i, l := 0, len(component); i < l/2; i++ {
+				component[i], component[l-1-i] = component[l-1-i], component[i]
+			}
+			cycles = append(cycles, component)
+		}
+	}
+	for _, p := range providers {
+		if _, has := graph[p]; !has {
+			continue
+		}
+		if _, has := indexes[p]; !has {
+			connect(p)
+		}
+	}
+	return cycles
+}